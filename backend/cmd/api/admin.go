@@ -0,0 +1,288 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type setRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
+type setScheduleRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+}
+
+// adminTargetUser loads the user named by the :id path parameter and checks
+// that the current user outranks them: moderators may only act on plain
+// users, admins on anyone but themselves. On failure it writes the response
+// and returns nil.
+func (app *application) adminTargetUser(c *gin.Context) *database.User {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil
+	}
+
+	target, err := app.models.Users.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return nil
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil
+	}
+
+	actor := app.getUserFromContext(c)
+	if target.Id == actor.Id {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change your own account here"})
+		return nil
+	}
+	if !actor.HasRole(database.RoleAdmin) && target.HasRole(database.RoleModerator) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return nil
+	}
+	return target
+}
+
+// adminListUsers handles GET /admin/users.
+//
+// @Summary List users
+// @Description Search users by email or name (moderators and admins)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Search text"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} database.User "Users"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/users [get]
+func (app *application) adminListUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	users, err := app.models.Users.Search(c.Query("q"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// adminSetRole handles PUT /admin/users/:id/role.
+//
+// @Summary Change a user's role
+// @Description Set the role of a user (admins only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param body body setRoleRequest true "New role"
+// @Success 200 {object} database.User "Updated user"
+// @Failure 400 {object} gin.H "Invalid request"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/users/{id}/role [put]
+func (app *application) adminSetRole(c *gin.Context) {
+	var input setRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target := app.adminTargetUser(c)
+	if target == nil {
+		return
+	}
+
+	if err := app.models.Users.SetRole(c.Request.Context(), target.Id, input.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	app.recordAudit(c, "admin.role_changed", &target.Id, map[string]any{
+		"by": app.getUserFromContext(c).Id, "from": target.Role, "to": input.Role,
+	})
+	target.Role = input.Role
+	c.JSON(http.StatusOK, target)
+}
+
+// adminDisableUser handles POST /admin/users/:id/disable.
+// The user's sessions and refresh tokens are revoked immediately.
+//
+// @Summary Disable a user
+// @Description Disable an account and end all of its sessions
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204 "User disabled"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/users/{id}/disable [post]
+func (app *application) adminDisableUser(c *gin.Context) {
+	app.adminSetDisabled(c, true)
+}
+
+// adminEnableUser handles POST /admin/users/:id/enable.
+//
+// @Summary Re-enable a user
+// @Description Re-enable a disabled account
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204 "User enabled"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/users/{id}/enable [post]
+func (app *application) adminEnableUser(c *gin.Context) {
+	app.adminSetDisabled(c, false)
+}
+
+func (app *application) adminSetDisabled(c *gin.Context, disabled bool) {
+	target := app.adminTargetUser(c)
+	if target == nil {
+		return
+	}
+
+	if err := app.models.Users.SetDisabled(c.Request.Context(), target.Id, disabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	action := "admin.user_enabled"
+	if disabled {
+		action = "admin.user_disabled"
+	}
+	app.recordAudit(c, action, &target.Id, map[string]any{"by": app.getUserFromContext(c).Id})
+	c.Status(http.StatusNoContent)
+}
+
+// adminDeleteEvent handles DELETE /admin/events/:id.
+//
+// @Summary Delete any event
+// @Description Remove an event regardless of its owner (moderators and admins)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 204 "Event deleted"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/events/{id} [delete]
+func (app *application) adminDeleteEvent(c *gin.Context) {
+	event := app.authorizeEvent(c, actionDeleteEvent)
+	if event == nil {
+		return
+	}
+
+	if err := app.models.Events.Delete(event.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	app.recordAudit(c, "admin.event_deleted", &user.Id, map[string]any{"event_id": event.Id, "owner_id": event.OwnerId})
+	c.Status(http.StatusNoContent)
+}
+
+// adminListInvalidSchedules handles GET /admin/events/invalid-schedules.
+// Events whose stored start or end cannot be read are left out of every
+// list and answer 422 when opened; this is where staff find them.
+//
+// @Summary List events with an invalid schedule
+// @Description Events whose stored start or end time cannot be read (moderators and admins)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} database.InvalidSchedule "Events with an invalid schedule"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/events/invalid-schedules [get]
+func (app *application) adminListInvalidSchedules(c *gin.Context) {
+	invalid, err := app.models.Events.InvalidSchedules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invalid)
+}
+
+// adminSetSchedule handles PUT /admin/events/:id/schedule. It overwrites
+// the start and end of any event, including one whose schedule cannot be
+// read and that therefore cannot be edited the usual way.
+//
+// @Summary Repair an event's schedule
+// @Description Set the start and end of an event, also when the stored ones are invalid (admins only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param body body setScheduleRequest true "New start and end"
+// @Success 204 "Schedule updated"
+// @Failure 400 {object} gin.H "Invalid request"
+// @Failure 403 {object} gin.H "Insufficient permissions"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/admin/events/{id}/schedule [put]
+func (app *application) adminSetSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var input setScheduleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.EndsAt.After(input.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	if err := app.models.Events.SetSchedule(c.Request.Context(), id, input.StartsAt, input.EndsAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	app.recordAudit(c, "admin.event_schedule_set", &user.Id, map[string]any{"event_id": id})
+	c.Status(http.StatusNoContent)
+}
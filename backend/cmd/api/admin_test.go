@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"testing"
+)
+
+func TestInvalidSchedules(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	good := newTestEvent(t, app, 0, "")
+	broken := *good
+	if err := app.models.Events.Insert(&broken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.models.Events.DB.Exec("UPDATE events SET starts_at = 'next tuesday' WHERE id = $1", broken.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := newTestUser(t, app, "admin")
+	if _, err := app.models.Users.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", database.RoleAdmin, admin.Id); err != nil {
+		t.Fatal(err)
+	}
+	auth := bearer(t, app, admin)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	searchCount := func() int {
+		t.Helper()
+		rec := do(http.MethodGet, "/api/v1/events/search?q=meetup", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search: status %d: %s", rec.Code, rec.Body)
+		}
+		var results []database.EventSearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		return len(results)
+	}
+
+	if n := searchCount(); n != 1 {
+		t.Errorf("search found %d events, want only the readable one", n)
+	}
+	if rec := do(http.MethodGet, fmt.Sprintf("/api/v1/events/%d", broken.Id), ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("opening the broken event: status %d, want 422", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/api/v1/admin/events/invalid-schedules", "")
+	var invalid []database.InvalidSchedule
+	if err := json.Unmarshal(rec.Body.Bytes(), &invalid); err != nil {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(invalid) != 1 || invalid[0].EventId != broken.Id || invalid[0].StartsAt == nil || *invalid[0].StartsAt != "next tuesday" {
+		t.Fatalf("invalid schedules = %s, want only event %d", rec.Body, broken.Id)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"end before start", fmt.Sprintf("/api/v1/admin/events/%d/schedule", broken.Id), `{"starts_at": "2030-01-01T19:00:00Z", "ends_at": "2030-01-01T18:00:00Z"}`, http.StatusBadRequest},
+		{"unknown event", "/api/v1/admin/events/999/schedule", `{"starts_at": "2030-01-01T18:00:00Z", "ends_at": "2030-01-01T19:00:00Z"}`, http.StatusNotFound},
+		{"repair", fmt.Sprintf("/api/v1/admin/events/%d/schedule", broken.Id), `{"starts_at": "2030-01-01T18:00:00Z", "ends_at": "2030-01-01T19:00:00Z"}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if rec := do(http.MethodPut, tt.path, tt.body); rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	if invalid, err := app.models.Events.InvalidSchedules(); err != nil || len(invalid) != 0 {
+		t.Errorf("after the repair: %d invalid schedules, %v", len(invalid), err)
+	}
+	if n := searchCount(); n != 2 {
+		t.Errorf("after the repair search found %d events, want 2", n)
+	}
+	if event, err := app.models.Events.Get(good.Id); err != nil || event == nil {
+		t.Errorf("the readable event: %v, %v", event, err)
+	}
+}
+
+func TestInvalidSchedulesNeedsStaff(t *testing.T) {
+	app := newTestApp(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/events/invalid-schedules", nil)
+	req.Header.Set("Authorization", bearer(t, app, newTestUser(t, app, "ann")))
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status %d, want 403", rec.Code)
+	}
+}
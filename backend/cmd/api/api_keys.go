@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyPrefix marks our keys so they are easy to spot in logs and secret
+// scanners.
+const apiKeyPrefix = "evk_"
+
+type createAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// Scope is "read" or "read_write"; it defaults to "read".
+	Scope     string     `json:"scope" binding:"omitempty,oneof=read read_write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createAPIKeyResponse is the only time the plaintext key is returned.
+type createAPIKeyResponse struct {
+	*database.APIKey
+	Key string `json:"key"`
+}
+
+// authenticateAPIKey is AuthMiddleware's path for "Authorization: ApiKey
+// <key>". It stores the user and the key in the context; there is no session.
+func (app *application) authenticateAPIKey(c *gin.Context, presented string) {
+	key, err := app.models.APIKeys.GetByHash(hashToken(presented))
+	if err != nil || key == nil || key.Expired(time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
+	}
+
+	user, err := app.models.Users.GetUserByID(key.UserId)
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		c.Abort()
+		return
+	}
+	if user.Disabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		c.Abort()
+		return
+	}
+
+	// Same once-a-minute rule as session last-seen
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > time.Minute {
+		if err := app.models.APIKeys.Touch(key.Id); err == nil {
+			now := time.Now().UTC()
+			key.LastUsedAt = &now
+		}
+	}
+
+	c.Set("user", user)
+	c.Set("apiKey", key)
+	c.Next()
+}
+
+// requireScope lets requests through when they were made with a session or
+// with an API key that has scope.
+func (app *application) requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !app.hasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this request"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasScope reports whether the current request may act with scope. Logged-in
+// users have every scope; a read-write key also covers read.
+func (app *application) hasScope(c *gin.Context, scope string) bool {
+	key := app.getAPIKeyFromContext(c)
+	if key == nil {
+		return true
+	}
+	return key.Scope == scope || key.Scope == database.APIKeyScopeReadWrite
+}
+
+// requireSession rejects API key requests. Account and credential management
+// need an interactive login, so a leaked key cannot mint new keys or change
+// the password.
+func (app *application) requireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if app.getSessionFromContext(c) == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// createAPIKey handles POST /auth/me/api-keys.
+//
+// @Summary Create an API key
+// @Description Create a named API key. The key is only shown in this response.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body createAPIKeyRequest true "Key name, scope and optional expiry"
+// @Success 201 {object} createAPIKeyResponse "Created key"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 403 {object} gin.H "Not allowed with an API key"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/api-keys [post]
+func (app *application) createAPIKey(c *gin.Context) {
+	var input createAPIKeyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Scope == "" {
+		input.Scope = database.APIKeyScopeRead
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate key"})
+		return
+	}
+	plaintext := apiKeyPrefix + secret
+
+	user := app.getUserFromContext(c)
+	key := database.APIKey{
+		UserId:    user.Id,
+		Name:      input.Name,
+		Prefix:    plaintext[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(plaintext),
+		Scope:     input.Scope,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := app.models.APIKeys.Insert(&key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create key"})
+		return
+	}
+
+	app.recordAudit(c, "api_key.created", &user.Id, map[string]any{"key_id": key.Id, "scope": key.Scope})
+	c.JSON(http.StatusCreated, createAPIKeyResponse{APIKey: &key, Key: plaintext})
+}
+
+// listAPIKeys handles GET /auth/me/api-keys.
+//
+// @Summary List my API keys
+// @Description List the authenticated user's API keys (without the secrets)
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} database.APIKey "API keys"
+// @Failure 403 {object} gin.H "Not allowed with an API key"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/api-keys [get]
+func (app *application) listAPIKeys(c *gin.Context) {
+	user := app.getUserFromContext(c)
+
+	keys, err := app.models.APIKeys.GetAllByUser(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// deleteAPIKey handles DELETE /auth/me/api-keys/:id.
+//
+// @Summary Delete an API key
+// @Description Revoke one of the authenticated user's API keys
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 204 "Key deleted"
+// @Failure 400 {object} gin.H "Invalid key ID"
+// @Failure 403 {object} gin.H "Not allowed with an API key"
+// @Failure 404 {object} gin.H "Key not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/api-keys/{id} [delete]
+func (app *application) deleteAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	if err := app.models.APIKeys.Delete(c.Request.Context(), user.Id, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete key"})
+		return
+	}
+
+	app.recordAudit(c, "api_key.deleted", &user.Id, map[string]any{"key_id": id})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestEvent creates an event owned by a new user. capacity 0 means
+// unlimited.
+func newTestEvent(t *testing.T, app *application, capacity int, rrule string) *database.Event {
+	t.Helper()
+	owner := newTestUser(t, app, "owner")
+	starts := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	event := &database.Event{
+		OwnerId: owner.Id, Name: "Meetup", Description: "A meetup for testing", Location: "Berlin",
+		Visibility: database.VisibilityPublic, StartsAt: starts, EndsAt: starts.Add(time.Hour),
+	}
+	if capacity > 0 {
+		event.Capacity = &capacity
+	}
+	if rrule != "" {
+		event.RRule = &rrule
+	}
+	if err := app.models.Events.Insert(event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func newTestUser(t *testing.T, app *application, name string) *database.User {
+	t.Helper()
+	user := &database.User{Email: name + "@example.com", Name: name, Password: "x"}
+	if err := app.models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestSeriesRSVPsTakeOccurrenceSpots(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	event := newTestEvent(t, app, 2, "FREQ=WEEKLY;COUNT=4")
+	first, second := "2030-01-07T18:00:00Z", "2030-01-14T18:00:00Z"
+
+	users := map[string]*database.User{}
+	for _, name := range []string{"ann", "bob", "cat", "dan", "eve"} {
+		users[name] = newTestUser(t, app, name)
+	}
+	respond := func(name, occurrence, status string) (*database.Attendee, []*database.Attendee) {
+		t.Helper()
+		attendee, promoted, err := app.models.Attendees.Respond(ctx, event.Id, occurrence, users[name].Id, status, nil)
+		if err != nil {
+			t.Fatalf("%s %s at %q: %v", name, status, occurrence, err)
+		}
+		return attendee, promoted
+	}
+	promotedNames := func(promoted []*database.Attendee) []string {
+		var names []string
+		for _, a := range promoted {
+			for name, u := range users {
+				if u.Id == a.UserId {
+					names = append(names, name+"@"+a.Occurrence)
+				}
+			}
+		}
+		return names
+	}
+
+	respond("ann", "", database.AttendeeGoing)
+	respond("bob", "", database.AttendeeGoing)
+
+	// Ann and Bob hold both spots at every occurrence
+	if a, _ := respond("cat", first, database.AttendeeGoing); a.Status != database.AttendeeWaitlisted {
+		t.Fatalf("cat at a full occurrence is %s, want waitlisted", a.Status)
+	}
+	if a, _ := respond("eve", second, database.AttendeeGoing); a.Status != database.AttendeeWaitlisted {
+		t.Fatalf("eve at a full occurrence is %s, want waitlisted", a.Status)
+	}
+
+	// Ann skipping the first occurrence frees her spot there
+	if _, promoted := respond("ann", first, database.AttendeeDeclined); len(promoted) != 1 || promoted[0].UserId != users["cat"].Id {
+		t.Errorf("ann declining the first occurrence promoted %v, want cat", promotedNames(promoted))
+	}
+
+	if a, _ := respond("dan", "", database.AttendeeGoing); a.Status != database.AttendeeWaitlisted {
+		t.Fatalf("dan on a full series is %s, want waitlisted", a.Status)
+	}
+	// Bob's series spot goes to Dan, who then holds it at the second
+	// occurrence too, so Eve keeps waiting
+	_, promoted := respond("bob", "", database.AttendeeCancelled)
+	if names := promotedNames(promoted); len(names) != 1 || names[0] != "dan@" {
+		t.Errorf("bob cancelling promoted %v, want only dan to the series", names)
+	}
+
+	stats, err := app.models.Attendees.CheckInStats(event.Id, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Registered != 2 {
+		t.Errorf("%d registered at the second occurrence, want 2", stats.Registered)
+	}
+}
+
+func TestRSVPCapacityAndWaitlist(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+	event := newTestEvent(t, app, 2, "")
+
+	users := map[string]*database.User{}
+	auth := map[string]string{}
+	for _, name := range []string{"ann", "bob", "cat", "dan"} {
+		users[name] = newTestUser(t, app, name)
+		auth[name] = bearer(t, app, users[name])
+	}
+
+	steps := []struct {
+		name   string
+		method string
+		want   string
+	}{
+		{"ann", http.MethodPost, database.AttendeeGoing},
+		{"bob", http.MethodPost, database.AttendeeGoing},
+		{"cat", http.MethodPost, database.AttendeeWaitlisted},
+		{"dan", http.MethodPost, database.AttendeeWaitlisted},
+		{"bob", http.MethodDelete, database.AttendeeCancelled},
+		// Bob's spot went to Cat, who joined the waitlist first
+		{"bob", http.MethodPost, database.AttendeeWaitlisted},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, fmt.Sprintf("/api/v1/events/%d/rsvp", event.Id), nil)
+		req.Header.Set("Authorization", auth[step.name])
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var attendee database.Attendee
+		if err := json.Unmarshal(rec.Body.Bytes(), &attendee); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d: %s", step.method, step.name, rec.Code, rec.Body)
+		}
+		if attendee.Status != step.want {
+			t.Errorf("%s %s: status %s, want %s", step.method, step.name, attendee.Status, step.want)
+		}
+	}
+
+	want := map[string]string{
+		"ann": database.AttendeeGoing,
+		"bob": database.AttendeeWaitlisted,
+		"cat": database.AttendeeGoing,
+		"dan": database.AttendeeWaitlisted,
+	}
+	for name, status := range want {
+		attendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, "", users[name].Id)
+		if err != nil || attendee == nil || attendee.Status != status {
+			t.Errorf("%s: %+v, %v; want %s", name, attendee, err, status)
+		}
+	}
+
+	// Dan has waited longer than Bob, who rejoined at the back
+	if _, promoted, err := app.models.Attendees.Respond(context.Background(), event.Id, "", users["ann"].Id, database.AttendeeDeclined, nil); err != nil {
+		t.Fatal(err)
+	} else if len(promoted) != 1 || promoted[0].UserId != users["dan"].Id {
+		t.Errorf("ann declining promoted %+v, want dan", promoted)
+	}
+}
+
+// TestConcurrentRSVPs checks that parallel RSVPs all succeed and never
+// overbook the event. Each RSVP counts the free spots and writes in one
+// transaction; the DSN's _txlock=immediate makes those transactions take the
+// write lock up front, so they queue on busy_timeout instead of failing with
+// SQLITE_BUSY when they upgrade from reading to writing.
+func TestConcurrentRSVPs(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+	const capacity, people = 3, 20
+	event := newTestEvent(t, app, capacity, "")
+
+	auth := make([]string, people)
+	for i := range auth {
+		auth[i] = bearer(t, app, newTestUser(t, app, fmt.Sprintf("user%d", i)))
+	}
+
+	var wg sync.WaitGroup
+	statuses := make([]string, people)
+	for i := range people {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/events/%d/rsvp", event.Id), nil)
+			req.Header.Set("Authorization", auth[i])
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			var attendee database.Attendee
+			if err := json.Unmarshal(rec.Body.Bytes(), &attendee); err != nil || rec.Code != http.StatusOK {
+				t.Errorf("RSVP %d: status %d: %s", i, rec.Code, rec.Body)
+			}
+			statuses[i] = attendee.Status
+		}()
+	}
+	wg.Wait()
+
+	count := map[string]int{}
+	for _, status := range statuses {
+		count[status]++
+	}
+	if count[database.AttendeeGoing] != capacity || count[database.AttendeeWaitlisted] != people-capacity {
+		t.Errorf("answers = %v, want %d going and the rest waitlisted", count, capacity)
+	}
+	stats, err := app.models.Attendees.CheckInStats(event.Id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Registered != capacity {
+		t.Errorf("%d registered, want %d", stats.Registered, capacity)
+	}
+}
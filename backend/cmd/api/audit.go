@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+	"rest-api-in-gin/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit writes an audit log entry for the current request. Audit
+// failures are logged but never fail the request itself.
+func (app *application) recordAudit(c *gin.Context, action string, userId *int, details map[string]any) {
+	entry := database.AuditEntry{
+		Action:    action,
+		UserId:    userId,
+		IPAddress: c.ClientIP(),
+		Details:   details,
+	}
+	if err := app.models.Audit.Insert(&entry); err != nil {
+		log.Printf("failed to write audit entry %q: %v", action, err)
+	}
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"rest-api-in-gin/internal/database"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -16,6 +17,10 @@ type registerRequest struct {
 	Email    string `json:"email" binding:"required,email"`    // Must be valid email format
 	Password string `json:"password" binding:"required,min=8"` // Minimum 8 characters for security
 	Name     string `json:"name" binding:"required,min=2"`     // Minimum 2 characters for user display name
+
+	// InvitationToken is set when signing up from an event invitation; the
+	// invitation is accepted for the new account right away.
+	InvitationToken string `json:"invitationToken"`
 }
 
 // loginRequest defines the expected JSON structure for user authentication requests.
@@ -26,24 +31,37 @@ type loginRequest struct {
 }
 
 // loginResponse defines the JSON structure returned upon successful authentication.
-// Contains the JWT token that clients should include in subsequent authenticated requests.
+// Token is the short-lived access JWT clients send in the Authorization header;
+// RefreshToken is an opaque value exchanged at /auth/refresh for a new pair.
 type loginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token lifetime in seconds
+}
+
+// refreshRequest carries the opaque refresh token for /auth/refresh and /auth/logout.
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // login handles POST /auth/login requests for user authentication.
-// It validates user credentials against the database and returns a JWT token upon success.
-// The returned token should be included in the Authorization header for protected endpoints.
+// It validates user credentials against the database and returns a short-lived
+// access token plus a refresh token. The access token should be included in the
+// Authorization header for protected endpoints. Accounts with two-factor
+// authentication get a challenge token instead, see loginTwoFactor.
 //
 // @Summary User login
-// @Description Authenticate user with email and password, returns JWT token
+// @Description Authenticate user with email and password, returns access and refresh tokens
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param credentials body loginRequest true "Login credentials"
 // @Success 200 {object} loginResponse "Login successful with JWT token"
+// @Success 200 {object} twoFactorChallengeResponse "Password accepted, 2FA code required"
 // @Failure 400 {object} gin.H "Invalid request body"
 // @Failure 401 {object} gin.H "Invalid credentials"
+// @Failure 403 {object} gin.H "Account is disabled"
+// @Failure 429 {object} gin.H "Too many failed attempts; see Retry-After"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /api/v1/auth/login [post]
 func (app *application) login(c *gin.Context) {
@@ -53,40 +71,68 @@ func (app *application) login(c *gin.Context) {
 		return
 	}
 
-	existingUser, err := app.models.Users.GetByEmail(auth.Email)
-	if existingUser == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	// Refuse to even compare passwords while the account or client is locked out
+	emailKey, ipKey := loginLimitKeys(c, auth.Email)
+	if !app.checkLoginLockout(c, emailKey, ipKey) {
 		return
 	}
+
+	existingUser, err := app.models.Users.GetByEmail(auth.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
 		return
 	}
+	if existingUser == nil {
+		if err := app.recordLoginFailure(c, auth.Email, emailKey, ipKey, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
 
 	// Compare provided password with stored bcrypt hash
 	// bcrypt.CompareHashAndPassword is constant-time to prevent timing attacks
 	err = bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(auth.Password))
 	if err != nil {
+		if err := app.recordLoginFailure(c, auth.Email, emailKey, ipKey, &existingUser.Id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	// Create JWT token with user ID and expiration claims
-	// Token expires in 72 hours for reasonable session duration
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId": existingUser.Id,
-		"exp":    time.Now().Add(time.Hour * 72).Unix(),
-	})
+	if err := app.loginLimiter.Success(c.Request.Context(), emailKey); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+		return
+	}
+
+	if existingUser.Disabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	// With 2FA enabled the password alone is not enough: hand out a challenge
+	// that has to be completed at /auth/login/2fa
+	if existingUser.TwoFactorEnabledAt != nil {
+		challenge, err := app.newTwoFactorChallenge(existingUser.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
 
-	// Sign the token with the application's secret key
-	tokenString, err := token.SignedString([]byte(app.jwtSecret))
+	// Record a session for this login and sign its access and refresh tokens
+	tokens, err := app.issueTokenPair(c, existingUser.Id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Return the signed JWT token for client-side storage and future requests
-	c.JSON(http.StatusOK, loginResponse{Token: tokenString})
+	c.JSON(http.StatusOK, tokens)
 }
 
 // registerUser handles POST /auth/register requests for new user account creation.
@@ -129,17 +175,134 @@ func (app *application) registerUser(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token for newly registered user using the persisted ID
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId": user.Id,
-		"exp":    time.Now().Add(time.Hour * 72).Unix(),
-	})
+	// Email delivery is best effort; the user can ask for a new link later
+	if err := app.sendVerificationEmail(&user); err != nil {
+		log.Printf("failed to create verification email for user %d: %v", user.Id, err)
+	}
 
-	tokenString, err := token.SignedString([]byte(app.jwtSecret))
+	// Issue tokens for the newly registered user using the persisted ID
+	tokens, err := app.issueTokenPair(c, user.Id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully", "user": user, "token": tokenString})
+	response := gin.H{
+		"message":       "User registered successfully",
+		"user":          user,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}
+	if register.InvitationToken != "" {
+		if attendee := app.acceptInvitationOnSignup(c, register.InvitationToken, &user); attendee != nil {
+			response["rsvp"] = attendee
+		}
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// refreshToken handles POST /auth/refresh requests.
+// The presented refresh token is rotated: it is revoked and a new access and
+// refresh token pair is returned. Presenting a token that was already rotated
+// revokes the whole token family, forcing the user to log in again.
+//
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access and refresh token pair
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body refreshRequest true "Refresh token"
+// @Success 200 {object} loginResponse "New token pair"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 401 {object} gin.H "Invalid, expired or reused refresh token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/refresh [post]
+func (app *application) refreshToken(c *gin.Context) {
+	var input refreshRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := app.rotateTokenPair(c.Request.Context(), input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrRefreshTokenReused):
+			log.Printf("refresh token reuse detected; token family revoked")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used"})
+		case errors.Is(err, database.ErrRefreshTokenRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has been revoked"})
+		case errors.Is(err, database.ErrRefreshTokenExpired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		case errors.Is(err, database.ErrRefreshTokenNotFound):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// logout handles POST /auth/logout requests.
+// It revokes the session and refresh token family the presented token belongs
+// to, so no further access tokens can be minted from this login and access
+// tokens already issued for the session stop being accepted.
+//
+// @Summary Logout
+// @Description Revoke the session and refresh token family for the current login
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body refreshRequest true "Refresh token"
+// @Success 204 "Logged out"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/logout [post]
+func (app *application) logout(c *gin.Context) {
+	var input refreshRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := app.models.RefreshTokens.GetByHash(hashToken(input.RefreshToken))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	// Unknown tokens are treated as already logged out; there is nothing to revoke
+	if token != nil {
+		if err := app.models.RefreshTokens.RevokeFamily(c.Request.Context(), token.FamilyId); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+		err := app.models.Sessions.Revoke(c.Request.Context(), token.UserId, token.SessionId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// jwks handles GET /.well-known/jwks.json.
+// It publishes the public halves of the RS256/EdDSA keys tokens are signed and
+// verified with, so other services can validate access tokens on their own.
+// The set is empty while the server signs with an HMAC secret.
+//
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify access tokens
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKS "Key set"
+// @Router /.well-known/jwks.json [get]
+func (app *application) jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, app.keys.JWKS())
 }
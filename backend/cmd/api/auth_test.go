@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(&database.User{Email: "ann@example.com", Name: "Ann", Password: string(hash)}); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		encoded, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	pair := func(rec *httptest.ResponseRecorder) loginResponse {
+		t.Helper()
+		var tokens loginResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		return tokens
+	}
+	login := func() loginResponse {
+		t.Helper()
+		return pair(do(http.MethodPost, "/api/v1/auth/login", "", loginRequest{Email: "ann@example.com", Password: "password123"}))
+	}
+	refresh := func(token string) *httptest.ResponseRecorder {
+		t.Helper()
+		return do(http.MethodPost, "/api/v1/auth/refresh", "", refreshRequest{RefreshToken: token})
+	}
+	me := func(tokens loginResponse) int {
+		t.Helper()
+		return do(http.MethodGet, "/api/v1/auth/me", "Bearer "+tokens.Token, nil).Code
+	}
+
+	first := login()
+	second := pair(refresh(first.RefreshToken))
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+	third := pair(refresh(second.RefreshToken))
+
+	other := login()
+	loggedOut := login()
+	if rec := do(http.MethodPost, "/api/v1/auth/logout", "", refreshRequest{RefreshToken: loggedOut.RefreshToken}); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/v1/auth/logout", "", refreshRequest{RefreshToken: "unknown"}); rec.Code != http.StatusNoContent {
+		t.Errorf("logout with an unknown token: status %d, want 204", rec.Code)
+	}
+
+	// Replaying a rotated token is treated as theft and ends the whole login
+	if rec := refresh(first.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replay: status %d, want 401: %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name         string
+		tokens       loginResponse
+		refreshCode  int
+		accessStatus int
+	}{
+		{"latest token of the replayed login", third, http.StatusUnauthorized, http.StatusUnauthorized},
+		{"rotated token of the replayed login", second, http.StatusUnauthorized, http.StatusUnauthorized},
+		{"logged out login", loggedOut, http.StatusUnauthorized, http.StatusUnauthorized},
+		{"other login", other, http.StatusOK, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status := me(tt.tokens); status != tt.accessStatus {
+			t.Errorf("%s: access token status %d, want %d", tt.name, status, tt.accessStatus)
+		}
+		if rec := refresh(tt.tokens.RefreshToken); rec.Code != tt.refreshCode {
+			t.Errorf("%s: refresh status %d, want %d: %s", tt.name, rec.Code, tt.refreshCode, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventAction is something a user may want to do with an event.
+type eventAction string
+
+const (
+	actionViewEvent        eventAction = "view"
+	actionViewOrganizers   eventAction = "view organizers for"
+	actionUpdateEvent      eventAction = "update"
+	actionDeleteEvent      eventAction = "delete"
+	actionViewAttendees    eventAction = "view attendees for"
+	actionManageAttendees  eventAction = "manage attendees for"
+	actionManageOrganizers eventAction = "manage organizers for"
+	actionTransferEvent    eventAction = "transfer"
+	actionCheckIn          eventAction = "check in attendees for"
+)
+
+// organizerPermissions lists what each organizer role may do. The owner may
+// do everything.
+var organizerPermissions = map[string][]eventAction{
+	database.OrganizerEditor:  {actionViewEvent, actionViewOrganizers, actionUpdateEvent, actionViewAttendees, actionManageAttendees, actionCheckIn},
+	database.OrganizerCheckIn: {actionViewEvent, actionViewOrganizers, actionViewAttendees, actionCheckIn},
+}
+
+// canOnEvent is the single place that decides who may do what with an event.
+// organizerRole is the user's role on the event's team ("" for outsiders and
+// anonymous visitors); guest is true when the user has an RSVP for the event,
+// usually from an invitation. Anyone may view events that are not private,
+// and guests may view private ones. Admins may do everything. Moderators may
+// look at any event and remove it, but not edit it or its attendee list.
+func (app *application) canOnEvent(user *database.User, event *database.Event, organizerRole string, guest bool, action eventAction) bool {
+	if organizerRole == database.OrganizerOwner || user.HasRole(database.RoleAdmin) {
+		return true
+	}
+	if action == actionViewEvent && (event.Visibility != database.VisibilityPrivate || guest) {
+		return true
+	}
+	for _, allowed := range organizerPermissions[organizerRole] {
+		if allowed == action {
+			return true
+		}
+	}
+	if user.HasRole(database.RoleModerator) {
+		switch action {
+		case actionViewEvent, actionViewOrganizers, actionViewAttendees, actionDeleteEvent:
+			return true
+		}
+	}
+	return false
+}
+
+// canViewAllEvents reports whether user may view every event, so lists of
+// events need no visibility filter for them. It is the part of canOnEvent's
+// view rule that does not depend on the event.
+func (app *application) canViewAllEvents(user *database.User) bool {
+	return user.HasRole(database.RoleModerator)
+}
+
+// authorizeEvent loads the event named by the :id path parameter and checks
+// that the current user may perform action on it. On failure it writes the
+// response and returns nil. Users who may not even view the event get 404 so
+// the endpoint does not reveal which ids exist.
+func (app *application) authorizeEvent(c *gin.Context, action eventAction) *database.Event {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil
+	}
+
+	event, failure := app.loadEventFor(app.getUserFromContext(c), id, action)
+	if failure != nil {
+		c.JSON(failure.status, gin.H{"error": failure.msg})
+		return nil
+	}
+	return event
+}
+
+// requestError is a failure together with the status and message to
+// answer it with.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+// loadEventFor is authorizeEvent for an event id that does not come from
+// the path, such as one item of a batch.
+func (app *application) loadEventFor(user *database.User, id int, action eventAction) (*database.Event, *requestError) {
+	event, err := app.models.Events.Get(id)
+	if errors.Is(err, database.ErrInvalidSchedule) {
+		log.Printf("failed to load event: %v", err)
+		return nil, &requestError{http.StatusUnprocessableEntity, "This event has an invalid start or end time"}
+	}
+	if err != nil {
+		return nil, &requestError{http.StatusInternalServerError, "Failed to retrieve event"}
+	}
+
+	if event == nil {
+		return nil, &requestError{http.StatusNotFound, "Event not found"}
+	}
+
+	role, err := app.models.Organizers.Role(event.Id, user.Id)
+	if err != nil {
+		return nil, &requestError{http.StatusInternalServerError, "Failed to retrieve event"}
+	}
+	// Only private events need the extra lookup to let invited guests in
+	guest := false
+	if role == "" && event.Visibility == database.VisibilityPrivate && user.Id != 0 {
+		guest, err = app.models.Attendees.HasRSVP(event.Id, user.Id)
+		if err != nil {
+			return nil, &requestError{http.StatusInternalServerError, "Failed to retrieve event"}
+		}
+	}
+	if !app.canOnEvent(user, event, role, guest, actionViewEvent) {
+		return nil, &requestError{http.StatusNotFound, "Event not found"}
+	}
+	if !app.canOnEvent(user, event, role, guest, action) {
+		return nil, &requestError{http.StatusForbidden, "You do not have permission to " + string(action) + " this event"}
+	}
+	return event, nil
+}
+
+// requireRole only lets users with role (or a more privileged one) through.
+func (app *application) requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !app.getUserFromContext(c).HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Batch modes.
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+// batchOperation is one item of POST /events/batch. Event is the event to
+// create, or the new state of the event Id for updates, exactly as the
+// body of POST /events or PUT /events/:id. Version plays the part of
+// If-Match: an update or delete with one only applies to that version.
+type batchOperation struct {
+	Op      string          `json:"op" binding:"required,oneof=create update delete"`
+	Id      int             `json:"id"`
+	Version int             `json:"version" binding:"min=0"`
+	Event   json.RawMessage `json:"event" swaggertype:"object"`
+}
+
+type batchRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []batchOperation `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+// batchResult is the outcome of one operation. Status is what the single
+// endpoint would have answered; 424 means the operation was fine but was
+// not applied because another one failed.
+type batchResult struct {
+	Index  int             `json:"index"`
+	Op     string          `json:"op"`
+	Status int             `json:"status"`
+	Id     int             `json:"id,omitempty"`
+	Event  *database.Event `json:"event,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Mode      string         `json:"mode"`
+	Committed bool           `json:"committed"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Results   []*batchResult `json:"results"`
+}
+
+// prepareBatchOp checks one operation the way createEvent, updateEvent and
+// deleteEvent check their requests, and returns what to run.
+func (app *application) prepareBatchOp(user *database.User, in batchOperation) (*database.BatchOp, *requestError) {
+	op := &database.BatchOp{Op: in.Op, Id: in.Id, IfVersion: in.Version}
+	if in.Op != database.BatchCreate && in.Id <= 0 {
+		return nil, &requestError{http.StatusBadRequest, "id is required"}
+	}
+	if in.Op == database.BatchCreate && in.Version != 0 {
+		return nil, &requestError{http.StatusBadRequest, "version only applies to update and delete"}
+	}
+	// The same rule as requireVerifiedEmail on POST /events
+	if in.Op == database.BatchCreate && app.requireEmailVerification && user.EmailVerifiedAt == nil {
+		return nil, &requestError{http.StatusForbidden, "Please verify your email address first"}
+	}
+
+	var existing *database.Event
+	switch in.Op {
+	case database.BatchUpdate, database.BatchDelete:
+		action := actionUpdateEvent
+		if in.Op == database.BatchDelete {
+			action = actionDeleteEvent
+		}
+		var failure *requestError
+		if existing, failure = app.loadEventFor(user, in.Id, action); failure != nil {
+			return nil, failure
+		}
+		if in.Version != 0 && in.Version != existing.Version {
+			return nil, &requestError{http.StatusPreconditionFailed, "The event was changed since you loaded it"}
+		}
+	}
+	if in.Op == database.BatchDelete {
+		return op, nil
+	}
+
+	if len(in.Event) == 0 || string(in.Event) == "null" {
+		return nil, &requestError{http.StatusBadRequest, "event is required"}
+	}
+	op.Event = &database.Event{}
+	if err := json.Unmarshal(in.Event, op.Event); err != nil {
+		return nil, &requestError{http.StatusBadRequest, err.Error()}
+	}
+	if err := binding.Validator.ValidateStruct(op.Event); err != nil {
+		return nil, &requestError{http.StatusBadRequest, err.Error()}
+	}
+
+	if existing != nil {
+		keepUnchanged(op.Event, existing)
+	} else {
+		op.Event.Id = 0
+		op.Event.OwnerId = user.Id
+	}
+	if err := checkSchedule(op.Event, existing); err != nil {
+		return nil, &requestError{http.StatusBadRequest, err.Error()}
+	}
+	if err := checkRecurrence(op.Event); err != nil {
+		return nil, &requestError{http.StatusBadRequest, err.Error()}
+	}
+	return op, nil
+}
+
+// batchEvents handles POST /events/batch. All operations run in one
+// transaction. In atomic mode (the default) any failure, including an
+// invalid operation, leaves everything unchanged; in best_effort mode the
+// operations that can be applied are, and the others are reported.
+//
+// @Summary Create, update and delete events in one request
+// @Description Run up to 100 create, update and delete operations in one transaction, all-or-nothing (atomic) or best_effort, with a result for every operation
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body batchRequest true "Mode and operations"
+// @Success 200 {object} batchResponse "Result of every operation"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/batch [post]
+func (app *application) batchEvents(c *gin.Context) {
+	var input batchRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Mode == "" {
+		input.Mode = batchAtomic
+	}
+	atomic := input.Mode != batchBestEffort
+
+	user := app.getUserFromContext(c)
+	results := make([]*batchResult, len(input.Operations))
+	var ops []*database.BatchOp
+	opResults := map[*database.BatchOp]*batchResult{}
+	invalid := false
+	for i, in := range input.Operations {
+		results[i] = &batchResult{Index: i, Op: in.Op, Id: in.Id}
+		op, failure := app.prepareBatchOp(user, in)
+		if failure != nil {
+			results[i].Status, results[i].Error = failure.status, failure.msg
+			invalid = true
+			continue
+		}
+		ops = append(ops, op)
+		opResults[op] = results[i]
+	}
+
+	response := batchResponse{Mode: input.Mode, Results: results}
+	if atomic && invalid {
+		for _, op := range ops {
+			notApplied(opResults[op])
+		}
+		respondBatch(c, response)
+		return
+	}
+
+	err := app.models.Events.Batch(c.Request.Context(), ops, atomic)
+	if err != nil && (!atomic || !opFailed(ops)) {
+		// The transaction itself failed
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run batch"})
+		return
+	}
+	response.Committed = err == nil
+
+	for _, op := range ops {
+		result := opResults[op]
+		switch {
+		case op.Err != nil:
+			result.Status, result.Error = http.StatusInternalServerError, "Failed to "+op.Op+" event"
+			switch {
+			case errors.Is(op.Err, sql.ErrNoRows):
+				result.Status, result.Error = http.StatusNotFound, "Event not found"
+			case errors.Is(op.Err, database.ErrVersionConflict):
+				result.Status, result.Error = http.StatusPreconditionFailed, "The event was changed since you loaded it"
+			}
+		case !response.Committed:
+			notApplied(result)
+		case op.Op == database.BatchCreate:
+			result.Status, result.Id, result.Event = http.StatusCreated, op.Event.Id, op.Event
+		case op.Op == database.BatchUpdate:
+			result.Status, result.Event = http.StatusOK, op.Event
+
+			// As in updateEvent, a larger limit makes room for the waitlist
+			promoted, err := app.models.Attendees.PromoteWaitlisted(c.Request.Context(), op.Event.Id)
+			if err != nil {
+				log.Printf("failed to promote waitlist of event %d: %v", op.Event.Id, err)
+			}
+			app.notifyPromoted(op.Event, promoted)
+		case op.Op == database.BatchDelete:
+			result.Status = http.StatusNoContent
+		}
+	}
+	respondBatch(c, response)
+}
+
+// opFailed reports whether one of ops failed, as opposed to the batch's
+// transaction.
+func opFailed(ops []*database.BatchOp) bool {
+	for _, op := range ops {
+		if op.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// notApplied marks a valid operation that was rolled back because another
+// one failed.
+func notApplied(result *batchResult) {
+	result.Status, result.Error = http.StatusFailedDependency, "Not applied because another operation failed"
+}
+
+// respondBatch counts the results and sends the response.
+func respondBatch(c *gin.Context, response batchResponse) {
+	for _, result := range response.Results {
+		if result.Status < 300 {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBatchModes(t *testing.T) {
+	// ID and STALE stand for the existing event's id and a version it is not at
+	const create = `{"op": "create", "event": {"name": "Workshop", "description": "A workshop for testing", "location": "Hamburg",
+		"starts_at": "2030-01-01T18:00:00Z", "ends_at": "2030-01-01T20:00:00Z"}}`
+	const staleUpdate = `{"op": "update", "id": ID, "version": STALE, "event": {"name": "Renamed", "description": "A meetup for testing", "location": "Berlin"}}`
+	const remove = `{"op": "delete", "id": ID}`
+
+	tests := []struct {
+		name        string
+		mode        string
+		ops         []string
+		want        []int
+		committed   bool
+		keptEvent   bool
+		totalEvents int
+	}{
+		{"atomic with an invalid operation", "atomic", []string{create, staleUpdate, remove},
+			[]int{http.StatusFailedDependency, http.StatusPreconditionFailed, http.StatusFailedDependency}, false, true, 1},
+		{"atomic is the default", "", []string{create, staleUpdate},
+			[]int{http.StatusFailedDependency, http.StatusPreconditionFailed}, false, true, 1},
+		{"atomic with a failing operation", "atomic", []string{create, remove, remove},
+			[]int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound}, false, true, 1},
+		{"best effort with an invalid operation", "best_effort", []string{create, staleUpdate, remove},
+			[]int{http.StatusCreated, http.StatusPreconditionFailed, http.StatusNoContent}, true, false, 1},
+		{"best effort with a failing operation", "best_effort", []string{create, remove, remove},
+			[]int{http.StatusCreated, http.StatusNoContent, http.StatusNotFound}, true, false, 1},
+		{"best effort without failures", "best_effort", []string{create, create},
+			[]int{http.StatusCreated, http.StatusCreated}, true, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+			event := newTestEvent(t, app, 0, "")
+			owner, err := app.models.Users.GetUserByID(event.OwnerId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			placeholders := strings.NewReplacer("ID", strconv.Itoa(event.Id), "STALE", strconv.Itoa(event.Version+1))
+			ops := make([]string, len(tt.ops))
+			for i, op := range tt.ops {
+				ops[i] = placeholders.Replace(op)
+			}
+			body := fmt.Sprintf(`{"mode": %q, "operations": [%s]}`, tt.mode, strings.Join(ops, ", "))
+			if tt.mode == "" {
+				body = fmt.Sprintf(`{"operations": [%s]}`, strings.Join(ops, ", "))
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/events/batch", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", bearer(t, app, owner))
+			rec := httptest.NewRecorder()
+			app.routes().ServeHTTP(rec, req)
+
+			var response batchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var statuses []int
+			for _, result := range response.Results {
+				statuses = append(statuses, result.Status)
+			}
+			if !slices.Equal(statuses, tt.want) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.want)
+			}
+			if response.Committed != tt.committed {
+				t.Errorf("committed = %v, want %v", response.Committed, tt.committed)
+			}
+			if failed := len(tt.want) - response.Succeeded; response.Failed != failed {
+				t.Errorf("failed = %d, want %d", response.Failed, failed)
+			}
+
+			if kept, err := app.models.Events.Get(event.Id); err != nil || (kept != nil) != tt.keptEvent {
+				t.Errorf("event kept = %v, %v; want %v", kept != nil, err, tt.keptEvent)
+			}
+			var total int
+			if err := app.models.Events.DB.QueryRow("SELECT COUNT(*) FROM events").Scan(&total); err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.totalEvents {
+				t.Errorf("%d events, want %d", total, tt.totalEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/ical"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	calendarProdID = "-//Event Management API//Events//EN"
+
+	// cancellationRetention is how long deleted events stay in feeds as
+	// cancelled; calendar applications refresh far more often than that.
+	cancellationRetention = 90 * 24 * time.Hour
+)
+
+// eventUID is the event's iCalendar UID. It must stay the same for as long
+// as the event exists so calendar applications update their copy instead
+// of adding another.
+func (app *application) eventUID(eventId int) string {
+	host := "localhost"
+	if u, err := url.Parse(app.frontendURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("event-%d@%s", eventId, host)
+}
+
+// icalEvents turns an event into VEVENTs: the event or series itself and,
+// for recurring events, one per occurrence that was changed on its own.
+func (app *application) icalEvents(event *database.Event) ([]*ical.Event, error) {
+	stamp := event.UpdatedAt
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	series := &ical.Event{
+		UID:         app.eventUID(event.Id),
+		Sequence:    event.Sequence,
+		Stamp:       stamp,
+		Start:       event.StartsAt,
+		End:         event.EndsAt,
+		TimeZone:    event.TimeLocation(),
+		Summary:     event.Name,
+		Description: event.Description,
+		Location:    event.Location,
+		URL:         fmt.Sprintf("%s/events/%d", app.frontendURL, event.Id),
+	}
+
+	rule, err := event.Rule()
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return []*ical.Event{series}, nil
+	}
+	series.RRule = rule.InLocation(event.TimeLocation()).String()
+	series.ExDates = event.ExDates
+
+	events := []*ical.Event{series}
+	modified, err := app.models.Occurrences.Modified(event)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range modified {
+		original, err := time.Parse(time.RFC3339, o.Occurrence)
+		if err != nil {
+			continue
+		}
+		changed := *series
+		changed.RRule, changed.ExDates = "", nil
+		changed.RecurrenceID = original
+		changed.Start, changed.End = o.StartsAt, o.EndsAt
+		changed.Summary, changed.Description, changed.Location = o.Name, o.Description, o.Location
+		events = append(events, &changed)
+	}
+	return events, nil
+}
+
+// writeCalendar sends cal as an .ics file.
+func writeCalendar(c *gin.Context, cal *ical.Calendar, filename string) {
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Status(http.StatusOK)
+	if _, err := cal.WriteTo(c.Writer); err != nil {
+		log.Printf("failed to write calendar %s: %v", filename, err)
+	}
+}
+
+// getEventICS handles GET /events/:id/ics. Anyone who can see the event can
+// download it.
+//
+// @Summary Download an event as iCalendar
+// @Description Get the event as an .ics file for Google Calendar, Outlook and the like. Recurring events include their rule, cancellations and changed occurrences.
+// @Tags Calendar
+// @Produce text/calendar
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {string} string "iCalendar file"
+// @Failure 400 {object} gin.H "Invalid event ID"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/ics [get]
+func (app *application) getEventICS(c *gin.Context) {
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
+		return
+	}
+
+	events, err := app.icalEvents(event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export event"})
+		return
+	}
+
+	writeCalendar(c, &ical.Calendar{ProdID: calendarProdID, Events: events}, fmt.Sprintf("event-%d.ics", event.Id))
+}
+
+// calendarFeedURL is the address calendar applications subscribe to.
+func calendarFeedURL(c *gin.Context, token string) string {
+	return fmt.Sprintf("%s/api/v1/calendar/%s.ics", requestBaseURL(c), url.PathEscape(token))
+}
+
+// createCalendarFeed handles POST /auth/me/calendar. It turns the feed on,
+// or gives it a new URL, which stops the old one from working. The URL is
+// only returned here.
+//
+// @Summary Create or rotate your calendar feed
+// @Description Get a secret URL that calendar applications can subscribe to. Calling it again replaces the URL.
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} gin.H "Feed, with its url"
+// @Failure 401 {object} gin.H "Unauthorized"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/calendar [post]
+func (app *application) createCalendarFeed(c *gin.Context) {
+	token, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	feed, err := app.models.Calendars.SetFeed(user.Id, hashToken(token))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"feed": feed, "url": calendarFeedURL(c, token)})
+}
+
+// getCalendarFeed handles GET /auth/me/calendar.
+//
+// @Summary Show your calendar feed
+// @Description Whether the feed is on and when it was last fetched. The URL is not shown again; rotate the feed to get a new one.
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} database.CalendarFeed "Feed"
+// @Failure 404 {object} gin.H "No calendar feed"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/calendar [get]
+func (app *application) getCalendarFeed(c *gin.Context) {
+	user := app.getUserFromContext(c)
+	feed, err := app.models.Calendars.GetFeed(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calendar feed"})
+		return
+	}
+	if feed == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// deleteCalendarFeed handles DELETE /auth/me/calendar.
+//
+// @Summary Turn your calendar feed off
+// @Description Stop the feed URL from working
+// @Tags Calendar
+// @Security BearerAuth
+// @Success 204 "Feed turned off"
+// @Failure 404 {object} gin.H "No calendar feed"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/calendar [delete]
+func (app *application) deleteCalendarFeed(c *gin.Context) {
+	user := app.getUserFromContext(c)
+	if err := app.models.Calendars.DeleteFeed(c.Request.Context(), user.Id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No calendar feed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar feed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// calendarFeed handles GET /calendar/:token. The token in the URL is the
+// only credential. The feed has the events the user organizes or is going
+// to, and those deleted in the last 90 days as cancelled.
+//
+// @Summary Calendar feed
+// @Description Subscribable iCalendar feed of your events, authenticated by the secret in the URL
+// @Tags Calendar
+// @Produce text/calendar
+// @Param token path string true "Feed token, optionally followed by .ics"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} gin.H "Calendar not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/calendar/{token} [get]
+func (app *application) calendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	feed, err := app.models.Calendars.GetFeedByHash(hashToken(token))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calendar"})
+		return
+	}
+	var user *database.User
+	if feed != nil {
+		user, err = app.models.Users.GetUserByID(feed.UserId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calendar"})
+			return
+		}
+	}
+	if user == nil || user.Disabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	organized, err := app.models.Events.GetAllByOrganizer(user.Id, database.ListParams{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calendar"})
+		return
+	}
+	attending, err := app.models.Attendees.GetEventsByAttendee(user.Id, nil, database.ListParams{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calendar"})
+		return
+	}
+	cancellations, err := app.models.Calendars.Cancellations(user.Id, time.Now().Add(-cancellationRetention))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calendar"})
+		return
+	}
+
+	cal := &ical.Calendar{ProdID: calendarProdID, Name: "Events"}
+	seen := map[int]bool{}
+	for _, event := range append(organized.Items, attending.Items...) {
+		if seen[event.Id] {
+			continue
+		}
+		seen[event.Id] = true
+		events, err := app.icalEvents(event)
+		if err != nil {
+			log.Printf("failed to add event %d to calendar of user %d: %v", event.Id, user.Id, err)
+			continue
+		}
+		cal.Events = append(cal.Events, events...)
+	}
+	for _, cc := range cancellations {
+		if seen[cc.EventId] {
+			continue
+		}
+		seen[cc.EventId] = true
+		loc, err := time.LoadLocation(cc.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		cal.Events = append(cal.Events, &ical.Event{
+			UID:      app.eventUID(cc.EventId),
+			Sequence: cc.Sequence,
+			Stamp:    cc.CancelledAt,
+			Start:    cc.StartsAt,
+			End:      cc.EndsAt,
+			TimeZone: loc,
+			Summary:  cc.Name,
+			Status:   ical.StatusCancelled,
+		})
+	}
+
+	// Same once-a-minute rule as API keys
+	if feed.LastUsedAt == nil || time.Since(*feed.LastUsedAt) > time.Minute {
+		if err := app.models.Calendars.TouchFeed(feed.Id); err != nil {
+			log.Printf("failed to record use of calendar feed %d: %v", feed.Id, err)
+		}
+	}
+
+	writeCalendar(c, cal, "events.ics")
+}
@@ -28,4 +28,34 @@ func (app *application) getUserFromContext(c *gin.Context) *database.User{
 
 	// Return the validated user object
 	return user
-}
\ No newline at end of file
+}
+
+// getSessionFromContext returns the session the current access token belongs
+// to, as stored by AuthMiddleware. It returns nil when no session is present.
+func (app *application) getSessionFromContext(c *gin.Context) *database.Session {
+	contextSession, exists := c.Get("session")
+	if !exists {
+		return nil
+	}
+
+	session, ok := contextSession.(*database.Session)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
+// getAPIKeyFromContext returns the API key the request was authenticated
+// with, or nil for requests made with an access token.
+func (app *application) getAPIKeyFromContext(c *gin.Context) *database.APIKey {
+	contextKey, exists := c.Get("apiKey")
+	if !exists {
+		return nil
+	}
+
+	key, ok := contextKey.(*database.APIKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
@@ -1,13 +1,69 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"rest-api-in-gin/internal/database"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// defaultEventLength is how long events last when no end is given.
+const defaultEventLength = time.Hour
+
+// validateSchedule checks the event's start, end and timezone with
+// checkSchedule. It writes a 400 response and returns false when the
+// schedule is invalid.
+func validateSchedule(c *gin.Context, event *database.Event, existing *database.Event) bool {
+	if err := checkSchedule(event, existing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// checkSchedule checks the event's start, end and timezone, filling in what
+// was left out: the start from the old date field, the timezone and length
+// from existing (when updating) or UTC and defaultEventLength.
+func checkSchedule(event *database.Event, existing *database.Event) error {
+	if event.StartsAt.IsZero() {
+		event.StartsAt = event.Date
+	}
+	if event.StartsAt.IsZero() {
+		return errors.New("starts_at is required")
+	}
+
+	if event.Timezone == "" {
+		event.Timezone = "UTC"
+		if existing != nil {
+			event.Timezone = existing.Timezone
+		}
+	}
+	if _, err := time.LoadLocation(event.Timezone); err != nil || event.Timezone == "Local" {
+		return errors.New("timezone must be an IANA name like Europe/Berlin")
+	}
+
+	if event.EndsAt.IsZero() {
+		length := defaultEventLength
+		if existing != nil {
+			length = existing.EndsAt.Sub(existing.StartsAt)
+		}
+		event.EndsAt = event.StartsAt.Add(length)
+	}
+	if !event.EndsAt.After(event.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
 // createEvent handles POST /events requests to create a new event.
 // It binds JSON request data to an Event struct, validates the input,
 // and inserts the event into the database via the Events model.
@@ -32,6 +88,10 @@ func (app *application) createEvent(c *gin.Context) {
 		return
 	}
 
+	if !validateSchedule(c, &event, nil) || !validateRecurrence(c, &event) {
+		return
+	}
+
 	user := app.getUserFromContext(c)
 	event.OwnerId = user.Id
 
@@ -40,34 +100,140 @@ func (app *application) createEvent(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", eventETag(&event))
 	c.JSON(http.StatusCreated, gin.H{"message": "Event created successfully", "event": event})
 }
 
-// getAllEvents returns events scoped to the authenticated owner.
+// getAllEvents returns the events the authenticated user organizes. Without
+// any list parameters it returns a plain array, as it always has.
 //
 // @Summary Returns all events for the current user
-// @Description Returns all events owned by the authenticated user
+// @Description Returns all events the authenticated user owns or co-organizes. Passing any of limit, cursor, sort or a filter returns a page envelope instead of an array.
 // @Tags Events
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param sort query string false "id (default), starts_at or name; prefix with - for descending"
+// @Param q query string false "Text to look for in name, description or location"
+// @Param location query string false "Location contains"
+// @Param date_from query string false "Only events on or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param date_to query string false "Only events on or before this time (RFC3339 or YYYY-MM-DD)"
 // @Success 200 {object} []database.Event
+// @Failure 400 {object} gin.H "Invalid list parameter"
 // @Router /api/v1/events [get]
 func (app *application) getAllEvents(c *gin.Context) {
+	params, paged, ok := readListParams(c, database.EventFilters)
+	if !ok {
+		return
+	}
+
 	user := app.getUserFromContext(c)
-	events, err := app.models.Events.GetAllByOwner(user.Id)
+	page, err := app.models.Events.GetAllByOrganizer(user.Id, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		listFailed(c, err, "events")
 		return
 	}
 
-	c.JSON(http.StatusOK, events)
+	respondList(c, page, paged)
 }
 
-// getEventByID handles GET /events/:id requests.
+// discoverFilterAliases maps the filter names discovery first shipped with
+// to the list filters that replaced them.
+var discoverFilterAliases = map[string]string{"from": "date_from", "to": "date_to"}
+
+// discoverEvents handles GET /events/discover. It lists public events for
+// anyone, signed in or not; private and unlisted events never show up here.
+// Results are always paged, soonest first by default. from and to, the
+// names the date filters first shipped with, still work.
+//
+// @Summary Discover public events
+// @Description Browse and search public events
+// @Tags Events
+// @Produce json
+// @Param q query string false "Text to look for in name, description or location"
+// @Param location query string false "Location contains"
+// @Param date_from query string false "Only events on or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param date_to query string false "Only events on or before this time (RFC3339 or YYYY-MM-DD)"
+// @Param from query string false "Deprecated name of date_from"
+// @Param to query string false "Deprecated name of date_to"
+// @Param sort query string false "starts_at (default), name or id; prefix with - for descending"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} database.Page[database.Event] "Events"
+// @Failure 400 {object} gin.H "Invalid query parameter"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/discover [get]
+func (app *application) discoverEvents(c *gin.Context) {
+	params, _, ok := readListParams(c, database.EventFilters)
+	if !ok {
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultPageSize
+	}
+	for old, name := range discoverFilterAliases {
+		value, found := c.GetQuery(old)
+		if _, set := params.Filters[name]; !found || set {
+			continue
+		}
+		if params.Filters == nil {
+			params.Filters = map[string]string{}
+		}
+		params.Filters[name] = value
+	}
+
+	page, err := app.models.Events.Discover(params)
+	if err != nil {
+		listFailed(c, err, "events")
+		return
+	}
+
+	respondList(c, page, true)
+}
+
+// searchEvents handles GET /events/search. Anonymous callers search public
+// events; signed-in users also find the events they organize.
+//
+// @Summary Search events
+// @Description Full-text search over event names, descriptions and locations, best matches first
+// @Tags Events
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param limit query int false "Maximum results (default 20, max 100)"
+// @Success 200 {array} database.EventSearchResult "Matching events"
+// @Failure 400 {object} gin.H "Missing or invalid query"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/search [get]
+func (app *application) searchEvents(c *gin.Context) {
+	q := c.Query("q")
+	if database.SearchQuery(q) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain at least one word"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	results, err := app.models.Events.Search(q, user.Id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// getEventByID handles GET /events/:id requests. Authentication is optional:
+// public and unlisted events are readable by anyone with the link.
 //
 // @Summary Get event by ID
-// @Description Retrieve a specific event by its ID
+// @Description Retrieve a specific event by its ID. Private events are only visible to their organizers.
 // @Tags Events
 // @Accept json
 // @Produce json
@@ -76,45 +242,107 @@ func (app *application) getAllEvents(c *gin.Context) {
 // @Success 200 {object} database.Event "Event details"
 // @Failure 400 {object} gin.H "Invalid event ID"
 // @Failure 404 {object} gin.H "Event not found"
+// @Failure 422 {object} gin.H "Event has an invalid start or end time"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /api/v1/events/{id} [get]
 func (app *application) getEventByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
 		return
 	}
 
-	user := app.getUserFromContext(c)
-	event, err := app.models.Events.Get(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+	etag := eventETag(event)
+	c.Header("ETag", etag)
+	if etagListed(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
-	if event == nil || event.OwnerId != user.Id {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
+	c.JSON(http.StatusOK, event)
+}
+
+// eventETag is the ETag of event: its version, which goes up with every
+// change.
+func eventETag(event *database.Event) string {
+	return fmt.Sprintf(`"%d"`, event.Version)
+}
+
+// etagListed reports whether header, an If-Match or If-None-Match value,
+// lists etag or is "*". Weak tags never match; events only have strong
+// ones.
+func etagListed(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion checks the If-Match header of a write to event. It returns
+// the version the write is conditional on, or 0 without a header. When the
+// client's copy is out of date it writes a 412 response with the current
+// event and returns false.
+func ifMatchVersion(c *gin.Context, event *database.Event) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	if !etagListed(header, eventETag(event)) {
+		preconditionFailed(c, event)
+		return 0, false
 	}
+	return event.Version, true
+}
 
-	c.JSON(http.StatusOK, event)
+// preconditionFailed answers a write based on an old copy of event.
+func preconditionFailed(c *gin.Context, event *database.Event) {
+	c.Header("ETag", eventETag(event))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The event was changed since you loaded it", "event": event})
 }
 
-// updateEvent handles PUT /events/:id to update an existing event.
-func (app *application) updateEvent(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+// saveEvent writes updated, an edited copy of existing, for PUT and PATCH.
+// ifVersion makes the write conditional as in EventModel.Update.
+func (app *application) saveEvent(c *gin.Context, updated *database.Event, ifVersion int) {
+	if err := app.models.Events.Update(updated, ifVersion); err != nil {
+		switch {
+		case errors.Is(err, database.ErrVersionConflict):
+			// Someone got in between; show them what is there now
+			if current, err := app.models.Events.Get(updated.Id); err == nil && current != nil {
+				preconditionFailed(c, current)
+				return
+			}
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The event was changed since you loaded it"})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+		}
+		return
+	}
+
+	// A larger (or removed) limit makes room for people on the waitlist
+	promoted, err := app.models.Attendees.PromoteWaitlisted(c.Request.Context(), updated.Id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
-		return
+		log.Printf("failed to promote waitlist of event %d: %v", updated.Id, err)
 	}
+	app.notifyPromoted(updated, promoted)
 
-	user := app.getUserFromContext(c)
-	existingEvent, err := app.models.Events.Get(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+	c.Header("ETag", eventETag(updated))
+	c.JSON(http.StatusOK, updated)
+}
+
+// updateEvent handles PUT /events/:id to update an existing event. Fields
+// added after the first version (visibility, capacity, rrule and exdates)
+// keep their current value when left out. With If-Match, the event is only
+// replaced if it still has that ETag.
+func (app *application) updateEvent(c *gin.Context) {
+	existingEvent := app.authorizeEvent(c, actionUpdateEvent)
+	if existingEvent == nil {
 		return
 	}
-	if existingEvent == nil || existingEvent.OwnerId != user.Id {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	ifVersion, ok := ifMatchVersion(c, existingEvent)
+	if !ok {
 		return
 	}
 
@@ -124,71 +352,195 @@ func (app *application) updateEvent(c *gin.Context) {
 		return
 	}
 
-	updatedEvent.Id = id
-	updatedEvent.OwnerId = user.Id
-
-	if err := app.models.Events.Update(updatedEvent); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+	keepUnchanged(updatedEvent, existingEvent)
+	if !validateSchedule(c, updatedEvent, existingEvent) || !validateRecurrence(c, updatedEvent) {
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedEvent)
+	app.saveEvent(c, updatedEvent, ifVersion)
 }
 
-// deleteEvent handles DELETE /events/:id requests.
-func (app *application) deleteEvent(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+// eventOutputFields are event fields clients cannot set. Patches that
+// mention them are not rejected, so clients can send back what they got.
+var eventOutputFields = []string{"id", "ownerId", "starts_at_local", "ends_at_local", "sequence", "updated_at", "version"}
+
+// mergePatch applies an RFC 7396 JSON Merge Patch to target: members of
+// patch replace those of target, objects are merged recursively, and null
+// removes a member.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// patchEvent handles PATCH /events/:id. The body is a JSON Merge Patch:
+// fields left out keep their value, and null clears capacity, rrule and
+// exdates or resets visibility and timezone to their defaults. Moving the
+// start without giving an end keeps the event's length. With If-Match, the
+// event is only changed if it still has that ETag.
+//
+// @Summary Change some fields of an event
+// @Description Update an event with a JSON Merge Patch (RFC 7396). Send If-Match with the ETag from GET to avoid overwriting someone else's changes.
+// @Tags Events
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param If-Match header string false "ETag the change is based on"
+// @Param patch body object true "Fields to change"
+// @Success 200 {object} database.Event "Updated event"
+// @Failure 400 {object} gin.H "Invalid patch or resulting event"
+// @Failure 403 {object} gin.H "Not allowed to update the event"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 412 {object} gin.H "The event was changed since the ETag was read"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id} [patch]
+func (app *application) patchEvent(c *gin.Context) {
+	existingEvent := app.authorizeEvent(c, actionUpdateEvent)
+	if existingEvent == nil {
+		return
+	}
+	ifVersion, ok := ifMatchVersion(c, existingEvent)
+	if !ok {
 		return
 	}
 
-	user := app.getUserFromContext(c)
-	existingEvent, err := app.models.Events.Get(id)
+	var patch map[string]any
+	body, err := c.GetRawData()
+	if err == nil {
+		err = json.Unmarshal(body, &patch)
+	}
+	if err != nil || patch == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The body must be a JSON object"})
+		return
+	}
+	for _, field := range eventOutputFields {
+		delete(patch, field)
+	}
+	// date is the old name of starts_at
+	if date, ok := patch["date"]; ok {
+		if _, ok := patch["starts_at"]; !ok {
+			patch["starts_at"] = date
+		}
+	}
+	delete(patch, "date")
+
+	current, err := json.Marshal(existingEvent)
+	var doc map[string]any
+	if err == nil {
+		err = json.Unmarshal(current, &doc)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
-	if existingEvent == nil || existingEvent.OwnerId != user.Id {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	delete(doc, "date")
+	merged, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
 
-	if err := app.models.Events.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+	updatedEvent := &database.Event{}
+	if err := json.Unmarshal(merged, updatedEvent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := binding.Validator.ValidateStruct(updatedEvent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	updatedEvent.Id = existingEvent.Id
+	updatedEvent.OwnerId = existingEvent.OwnerId
+	if updatedEvent.Visibility == "" {
+		updatedEvent.Visibility = database.VisibilityPrivate
+	}
+	if _, ok := patch["ends_at"]; !ok {
+		if _, ok := patch["starts_at"]; ok {
+			updatedEvent.EndsAt = updatedEvent.StartsAt.Add(existingEvent.EndsAt.Sub(existingEvent.StartsAt))
+		}
+	}
+	if updatedEvent.Timezone == "" {
+		updatedEvent.Timezone = "UTC"
+	}
+	if !validateSchedule(c, updatedEvent, existingEvent) || !validateRecurrence(c, updatedEvent) {
+		return
+	}
+
+	app.saveEvent(c, updatedEvent, ifVersion)
 }
 
-// addAttendeeToEvent handles POST /events/:id/attendees/:userId.
-func (app *application) addAttendeeToEvent(c *gin.Context) {
-	eventId, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+// keepUnchanged prepares updated to replace existing: it keeps the id and
+// owner, and the fields added after the first version of the API when they
+// were left out.
+func keepUnchanged(updated, existing *database.Event) {
+	updated.Id = existing.Id
+	updated.OwnerId = existing.OwnerId
+	if updated.Visibility == "" {
+		updated.Visibility = existing.Visibility
+	}
+	if updated.Capacity == nil {
+		updated.Capacity = existing.Capacity
+	}
+	if updated.RRule == nil {
+		updated.RRule = existing.RRule
+	}
+	if updated.ExDates == nil {
+		updated.ExDates = existing.ExDates
+	}
+}
+
+// deleteEvent handles DELETE /events/:id requests.
+func (app *application) deleteEvent(c *gin.Context) {
+	existingEvent := app.authorizeEvent(c, actionDeleteEvent)
+	if existingEvent == nil {
 		return
 	}
 
-	userId, err := strconv.Atoi(c.Param("userId"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	if err := app.models.Events.Delete(existingEvent.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
 		return
 	}
 
-	event, err := app.models.Events.Get(eventId)
+	c.Status(http.StatusNoContent)
+}
+
+// addAttendeeToEvent handles POST /events/:id/attendees/:userId. The
+// occurrence query parameter adds the user to one occurrence of a recurring
+// event only. An optional body records registration answers on the user's
+// behalf; required questions may be left for them to answer.
+func (app *application) addAttendeeToEvent(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
-	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
+
+	var input answersRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	user := app.getUserFromContext(c)
-	if event.OwnerId != user.Id {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add attendees to this event"})
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
 		return
 	}
 
@@ -202,18 +554,23 @@ func (app *application) addAttendeeToEvent(c *gin.Context) {
 		return
 	}
 
-	existingAttendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, userToAdd.Id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendee"})
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
 		return
 	}
-	if existingAttendee != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User is already an attendee"})
+	answers, ok := app.checkAnswers(c, event, occurrence, userToAdd.Id, input.Answers, false)
+	if !ok {
 		return
 	}
 
-	attendee := database.Attendee{EventId: event.Id, UserId: userToAdd.Id}
-	if _, err := app.models.Attendees.Insert(&attendee); err != nil {
+	// Organizers can admit people past the capacity, including from the
+	// waitlist
+	attendee, err := app.models.Attendees.Admit(c.Request.Context(), event.Id, occurrence, userToAdd.Id, answers)
+	if errors.Is(err, database.ErrAlreadyGoing) {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already an attendee"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add attendee"})
 		return
 	}
@@ -221,78 +578,62 @@ func (app *application) addAttendeeToEvent(c *gin.Context) {
 	c.JSON(http.StatusCreated, attendee)
 }
 
-// getAttendeesForEvent handles GET /events/:id/attendees.
+// getAttendeesForEvent handles GET /events/:id/attendees. It takes the same
+// list parameters as GET /events, with the filters q, name, email, status
+// and occurrence and the sorts joined (default), name and email.
 func (app *application) getAttendeesForEvent(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+	params, paged, ok := readListParams(c, database.AttendeeFilters)
+	if !ok {
 		return
 	}
 
-	event, err := app.models.Events.Get(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
-		return
-	}
+	event := app.authorizeEvent(c, actionViewAttendees)
 	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	user := app.getUserFromContext(c)
-	if event.OwnerId != user.Id {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view attendees for this event"})
-		return
-	}
-
-	users, err := app.models.Attendees.GetAttendeesByEvent(id)
+	page, err := app.models.Attendees.GetAttendeesByEvent(event.Id, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendees"})
+		listFailed(c, err, "attendees")
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	respondList(c, page, paged)
 }
 
 // deleteAttendeeFromEvent handles DELETE /events/:id/attendees/:userId.
+// Without the occurrence query parameter the user is removed from every
+// occurrence.
 func (app *application) deleteAttendeeFromEvent(c *gin.Context) {
-	eventId, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
-		return
-	}
-
 	userId, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	event, err := app.models.Events.Get(eventId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
-		return
-	}
+	event := app.authorizeEvent(c, actionManageAttendees)
 	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	user := app.getUserFromContext(c)
-	if event.OwnerId != user.Id {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete an attendee from this event"})
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
 		return
 	}
 
-	if err := app.models.Attendees.DeleteByEventAndUser(userId, eventId); err != nil {
+	promoted, err := app.models.Attendees.DeleteByEventAndUser(userId, event.Id, occurrence)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attendee"})
 		return
 	}
+	app.notifyPromoted(event, promoted)
 
 	c.Status(http.StatusNoContent)
 }
 
 // getEventsByAttendee handles GET /events/:id/attendees/:userId requests to retrieve all events for a user.
+// Callers only get the events they could find themselves: public ones and
+// the ones they organize or were invited to. Moderators and admins see all.
 func (app *application) getEventsByAttendee(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
@@ -300,11 +641,20 @@ func (app *application) getEventsByAttendee(c *gin.Context) {
 		return
 	}
 
-	events, err := app.models.Attendees.GetEventsByAttendee(id)
+	params, paged, ok := readListParams(c, database.EventFilters)
+	if !ok {
+		return
+	}
+
+	var viewerId *int
+	if user := app.getUserFromContext(c); !app.canViewAllEvents(user) {
+		viewerId = &user.Id
+	}
+	page, err := app.models.Attendees.GetEventsByAttendee(id, viewerId, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		listFailed(c, err, "events")
 		return
 	}
 
-	c.JSON(http.StatusOK, events)
+	respondList(c, page, paged)
 }
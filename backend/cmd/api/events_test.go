@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"rest-api-in-gin/internal/database"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiscoverDateFilters(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	owner := database.User{Email: "ann@example.com", Name: "Ann", Password: "x"}
+	if err := app.models.Users.Insert(&owner); err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []int{1, 15, 30} {
+		starts := time.Date(2025, 6, day, 18, 0, 0, 0, time.UTC)
+		event := database.Event{
+			OwnerId: owner.Id, Name: "Meetup", Description: "A monthly meetup", Location: "Berlin",
+			Visibility: database.VisibilityPublic, StartsAt: starts, EndsAt: starts.Add(2 * time.Hour),
+		}
+		if err := app.models.Events.Insert(&event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?date_from=2025-06-10&date_to=2025-06-20", 1},
+		{"?from=2025-06-10&to=2025-06-20", 1},
+		{"?from=2025-06-10", 2},
+		// The current names win over the old ones
+		{"?date_from=2025-06-20&from=2025-06-01", 1},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events/discover"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", tt.query, rec.Code, rec.Body)
+			continue
+		}
+		var page database.Page[database.Event]
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != tt.want || page.Total != tt.want {
+			t.Errorf("%s: %d items, total %d; want %d", tt.query, len(page.Items), page.Total, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events/discover?from=June", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid from: status %d, want 400", rec.Code)
+	}
+}
+
+func TestEventsByAttendeeHidesPrivateEvents(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	users := map[string]*database.User{}
+	for _, name := range []string{"ann", "bob", "eve", "mod"} {
+		user := &database.User{Email: name + "@example.com", Name: name, Password: "x"}
+		if name == "mod" {
+			user.Role = database.RoleModerator
+		}
+		if err := app.models.Users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user
+	}
+
+	// Bob goes to a public, an unlisted and a private event of Ann's; Eve
+	// was invited to the private one
+	starts := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	events := map[string]int{}
+	for _, visibility := range []string{database.VisibilityPublic, database.VisibilityUnlisted, database.VisibilityPrivate} {
+		event := database.Event{
+			OwnerId: users["ann"].Id, Name: "Event " + visibility, Description: "An event for testing", Location: "Berlin",
+			Visibility: visibility, StartsAt: starts, EndsAt: starts.Add(time.Hour),
+		}
+		if err := app.models.Events.Insert(&event); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := app.models.Attendees.Admit(context.Background(), event.Id, "", users["bob"].Id, nil); err != nil {
+			t.Fatal(err)
+		}
+		events[visibility] = event.Id
+	}
+	if _, err := app.models.Attendees.Admit(context.Background(), events[database.VisibilityPrivate], "", users["eve"].Id, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		caller string
+		want   []string
+	}{
+		{"ann", []string{database.VisibilityPublic, database.VisibilityUnlisted, database.VisibilityPrivate}},
+		{"bob", []string{database.VisibilityPublic, database.VisibilityUnlisted, database.VisibilityPrivate}},
+		{"eve", []string{database.VisibilityPublic, database.VisibilityPrivate}},
+		{"mod", []string{database.VisibilityPublic, database.VisibilityUnlisted, database.VisibilityPrivate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller, func(t *testing.T) {
+			caller := users[tt.caller]
+			session := database.Session{UserId: caller.Id}
+			if err := app.models.Sessions.Insert(&session); err != nil {
+				t.Fatal(err)
+			}
+			token, err := app.newAccessToken(caller.Id, session.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/events/%d/attendees/%d", events[database.VisibilityPublic], users["bob"].Id), nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var got []database.Event
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			var visibilities []string
+			for _, event := range got {
+				visibilities = append(visibilities, event.Visibility)
+			}
+			if !slices.Equal(visibilities, tt.want) {
+				t.Errorf("sees %v, want %v", visibilities, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples from RFC 7396, appendix A
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		var target, patch, want any
+		for _, v := range []struct {
+			doc  string
+			dest *any
+		}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+			if err := json.Unmarshal([]byte(v.doc), v.dest); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestConditionalEventWrites(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+	event := newTestEvent(t, app, 10, "")
+	owner, err := app.models.Users.GetUserByID(event.OwnerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := bearer(t, app, owner)
+	path := fmt.Sprintf("/api/v1/events/%d", event.Id)
+	stale := eventETag(event)
+
+	// current stands for the event's ETag before the step
+	const current = "current"
+	tests := []struct {
+		name        string
+		method      string
+		ifMatch     string
+		ifNoneMatch string
+		body        string
+		status      int
+		check       func(*database.Event) bool
+	}{
+		{name: "conditional read", method: http.MethodGet, ifNoneMatch: current, status: http.StatusNotModified},
+		{name: "read of another version", method: http.MethodGet, ifNoneMatch: `"999"`, status: http.StatusOK},
+		{name: "patch without If-Match", method: http.MethodPatch, body: `{"name": "Renamed"}`, status: http.StatusOK,
+			check: func(e *database.Event) bool { return e.Name == "Renamed" && *e.Capacity == 10 }},
+		{name: "patch of an old version", method: http.MethodPatch, ifMatch: stale, body: `{"name": "Lost"}`, status: http.StatusPreconditionFailed,
+			check: func(e *database.Event) bool { return e.Name == "Renamed" }},
+		{name: "put of an old version", method: http.MethodPut, ifMatch: stale,
+			body: `{"name": "Lost", "description": "A meetup for testing", "location": "Berlin"}`, status: http.StatusPreconditionFailed,
+			check: func(e *database.Event) bool { return e.Name == "Renamed" }},
+		{name: "null clears capacity and resets visibility", method: http.MethodPatch, ifMatch: current,
+			body: `{"capacity": null, "visibility": null}`, status: http.StatusOK,
+			check: func(e *database.Event) bool { return e.Capacity == nil && e.Visibility == database.VisibilityPrivate }},
+		{name: "null for a required field", method: http.MethodPatch, ifMatch: current, body: `{"name": null}`, status: http.StatusBadRequest,
+			check: func(e *database.Event) bool { return e.Name == "Renamed" }},
+		{name: "moving the start keeps the length", method: http.MethodPatch, ifMatch: `"999", ` + current,
+			body: `{"starts_at": "2030-01-01T18:00:00Z"}`, status: http.StatusOK,
+			check: func(e *database.Event) bool { return e.EndsAt.Equal(time.Date(2030, 1, 1, 19, 0, 0, 0, time.UTC)) }},
+		{name: "output fields are ignored", method: http.MethodPatch, ifMatch: "*", body: `{"id": 999, "version": 1, "location": "Paris"}`, status: http.StatusOK,
+			check: func(e *database.Event) bool { return e.Id == event.Id && e.Location == "Paris" }},
+		{name: "not a JSON object", method: http.MethodPatch, body: `["name"]`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		before, err := app.models.Events.Get(event.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(tt.method, path, bytes.NewBufferString(tt.body))
+		req.Header.Set("Authorization", auth)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		for header, value := range map[string]string{"If-Match": tt.ifMatch, "If-None-Match": tt.ifNoneMatch} {
+			if value != "" {
+				req.Header.Set(header, strings.ReplaceAll(value, current, eventETag(before)))
+			}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+			continue
+		}
+
+		after, err := app.models.Events.Get(event.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.check != nil && !tt.check(after) {
+			t.Errorf("%s: event is %+v", tt.name, after)
+		}
+		if rec.Code != http.StatusBadRequest && rec.Header().Get("ETag") != eventETag(after) {
+			t.Errorf("%s: ETag %q, want %q", tt.name, rec.Header().Get("ETag"), eventETag(after))
+		}
+		if changed := after.Version != before.Version; changed != (tt.method != http.MethodGet && rec.Code == http.StatusOK) {
+			t.Errorf("%s: version went from %d to %d", tt.name, before.Version, after.Version)
+		}
+	}
+}
+
+func TestSearchTakesSyntaxLiterally(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+	newTestEvent(t, app, 0, "")
+
+	tests := []struct {
+		q      string
+		status int
+		found  int
+	}{
+		{"berlin testing", http.StatusOK, 1},
+		{"berlin paris", http.StatusOK, 0},
+		{"meet", http.StatusOK, 1},
+		{`"meetup`, http.StatusOK, 1},
+		{"(meetup)", http.StatusOK, 1},
+		{"meet*", http.StatusOK, 1},
+		{"meetup OR paris", http.StatusOK, 0},
+		{"NOT paris", http.StatusOK, 0},
+		{"location:berlin", http.StatusOK, 0},
+		{"NEAR(meetup berlin)", http.StatusOK, 0},
+		{`* "`, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events/search?q="+url.QueryEscape(tt.q), nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.q, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var results []database.EventSearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != tt.found {
+			t.Errorf("%s: found %d events, want %d", tt.q, len(results), tt.found)
+		}
+	}
+}
+
+func TestAddOrganizerConcurrently(t *testing.T) {
+	app := newTestApp(t)
+	event := newTestEvent(t, app, 0, "")
+
+	const users, requests = 10, 10
+	for u := range users {
+		user := newTestUser(t, app, fmt.Sprintf("user%d", u))
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs := make([]error, requests)
+		for i := range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs[i] = app.models.Organizers.Add(context.Background(), event.Id, user.Id, database.OrganizerEditor)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		added := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				added++
+			case !errors.Is(err, database.ErrOrganizerExists):
+				t.Errorf("adding %s: %v", user.Name, err)
+			}
+		}
+		if added != 1 {
+			t.Errorf("%s was added %d times, want once", user.Name, added)
+		}
+	}
+}
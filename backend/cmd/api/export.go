@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/xlsx"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushRows is how many rows an export writes between flushes, so
+// clients see progress on long lists.
+const exportFlushRows = 500
+
+// exportColumn is a column attendee exports can include.
+type exportColumn struct {
+	title string
+	value func(row *database.ExportRow, loc *time.Location) any
+}
+
+// exportColumns are the columns attendee exports offer, by the name used in
+// the columns query parameter. Times are given in the event's timezone.
+var exportColumns = map[string]exportColumn{
+	"user_id": {"User ID", func(r *database.ExportRow, _ *time.Location) any { return r.UserId }},
+	"name":    {"Name", func(r *database.ExportRow, _ *time.Location) any { return r.Name }},
+	"email":   {"Email", func(r *database.ExportRow, _ *time.Location) any { return r.Email }},
+	"status":  {"Status", func(r *database.ExportRow, _ *time.Location) any { return r.Status }},
+	"occurrence": {"Occurrence", func(r *database.ExportRow, _ *time.Location) any {
+		if r.Occurrence == "" {
+			return nil
+		}
+		return r.Occurrence
+	}},
+	"rsvp_at": {"RSVP time", func(r *database.ExportRow, loc *time.Location) any {
+		if r.RespondedAt.IsZero() {
+			return nil
+		}
+		return r.RespondedAt.In(loc).Format(time.RFC3339)
+	}},
+	"checked_in": {"Checked in", func(r *database.ExportRow, _ *time.Location) any { return r.CheckedInAt != nil }},
+	"checked_in_at": {"Check-in time", func(r *database.ExportRow, loc *time.Location) any {
+		if r.CheckedInAt == nil {
+			return nil
+		}
+		return r.CheckedInAt.In(loc).Format(time.RFC3339)
+	}},
+}
+
+// defaultExportColumns are exported when the columns parameter is absent.
+var defaultExportColumns = []string{"name", "email", "status", "rsvp_at", "checked_in", "checked_in_at"}
+
+// exportWriter writes one export format. Begin writes the column titles,
+// Row a row of values from exportColumns, and End anything that has to
+// follow the last row.
+type exportWriter interface {
+	Begin(names, titles []string) error
+	Row(values []any) error
+	Flush() error
+	End() error
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func (e *csvExport) Begin(_, titles []string) error {
+	return e.w.Write(titles)
+}
+
+func (e *csvExport) Row(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			record[i] = csvSafe(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport) End() error {
+	return e.Flush()
+}
+
+// csvSafe keeps spreadsheet applications from running text that starts
+// like a formula, such as a name entered as "=HYPERLINK(...)".
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type jsonlExport struct {
+	enc   *json.Encoder
+	names []string
+}
+
+func (e *jsonlExport) Begin(names, _ []string) error {
+	e.names = names
+	return nil
+}
+
+func (e *jsonlExport) Row(values []any) error {
+	obj := make(map[string]any, len(values))
+	for i, v := range values {
+		obj[e.names[i]] = v
+	}
+	return e.enc.Encode(obj)
+}
+
+func (e *jsonlExport) Flush() error { return nil }
+func (e *jsonlExport) End() error   { return nil }
+
+type xlsxExport struct {
+	w *xlsx.Writer
+}
+
+func (e *xlsxExport) Begin(_, titles []string) error {
+	return e.w.WriteHeader(titles)
+}
+
+func (e *xlsxExport) Row(values []any) error {
+	return e.w.WriteRow(values)
+}
+
+func (e *xlsxExport) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *xlsxExport) End() error {
+	return e.w.Close()
+}
+
+// exportFilename is the download name of an export of event, made from its
+// name so that downloads of several events are told apart.
+func exportFilename(event *database.Event, ext string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(event.Name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 50 {
+			break
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		slug = fmt.Sprintf("event-%d", event.Id)
+	}
+	return slug + "-attendees." + ext
+}
+
+// exportAttendees handles GET /events/:id/attendees/export. Rows are read
+// from the database a page at a time and written to the client as they
+// come, so exports of any size need little memory. It takes the filters and sorts of the
+// attendee list; limit and cursor are ignored and every matching row is
+// exported.
+//
+// @Summary Export attendees
+// @Description Download the attendee list as CSV, Excel or JSON Lines. The filters and sorts of the attendee list apply.
+// @Tags Attendees
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param format query string false "csv (default), xlsx or jsonl"
+// @Param columns query string false "Comma-separated columns: user_id, name, email, status, occurrence, rsvp_at, checked_in, checked_in_at"
+// @Param status query string false "going (default), waitlisted, declined or cancelled"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Param checked_in query string false "true or false"
+// @Param sort query string false "joined (default), name or email; prefix with - for descending"
+// @Success 200 {file} file "Attendee list"
+// @Failure 400 {object} gin.H "Invalid format, columns or list parameters"
+// @Failure 403 {object} gin.H "Not allowed to view attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/attendees/export [get]
+func (app *application) exportAttendees(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, xlsx, jsonl"})
+		return
+	}
+
+	names := defaultExportColumns
+	if value := c.Query("columns"); value != "" {
+		names = strings.Split(value, ",")
+		for i, name := range names {
+			names[i] = strings.TrimSpace(name)
+			if _, ok := exportColumns[names[i]]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "columns must be chosen from " + strings.Join(sortedColumnNames(), ", ")})
+				return
+			}
+		}
+	}
+	titles := make([]string, len(names))
+	for i, name := range names {
+		titles[i] = exportColumns[name].title
+	}
+
+	params, _, ok := readListParams(c, database.AttendeeFilters)
+	if !ok {
+		return
+	}
+	params.Limit, params.Cursor = 0, ""
+
+	event := app.authorizeEvent(c, actionViewAttendees)
+	if event == nil {
+		return
+	}
+	loc := event.TimeLocation()
+
+	// Nothing is sent until the query has started, so bad filters still get
+	// a proper 400
+	var out exportWriter
+	start := func() error {
+		filename := exportFilename(event, format)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		switch format {
+		case "xlsx":
+			c.Header("Content-Type", xlsx.ContentType)
+			w, err := xlsx.NewWriter(c.Writer, event.Name)
+			if err != nil {
+				return err
+			}
+			out = &xlsxExport{w: w}
+		case "jsonl":
+			c.Header("Content-Type", "application/x-ndjson")
+			out = &jsonlExport{enc: json.NewEncoder(c.Writer)}
+		default:
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			out = &csvExport{w: csv.NewWriter(c.Writer)}
+		}
+		c.Status(http.StatusOK)
+		return out.Begin(names, titles)
+	}
+
+	n := 0
+	err := app.models.Attendees.Export(c.Request.Context(), event.Id, params, func(row *database.ExportRow) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		values := make([]any, len(names))
+		for i, name := range names {
+			values[i] = exportColumns[name].value(row, loc)
+		}
+		if err := out.Row(values); err != nil {
+			return err
+		}
+		if n++; n%exportFlushRows == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil && out == nil {
+		// No rows: still send the column titles
+		err = start()
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			listFailed(c, err, "attendees")
+			return
+		}
+		// Too late for an error response; the file is cut short instead
+		log.Printf("failed to export attendees of event %d: %v", event.Id, err)
+		return
+	}
+	if err := out.End(); err != nil {
+		log.Printf("failed to export attendees of event %d: %v", event.Id, err)
+	}
+}
+
+func sortedColumnNames() []string {
+	names := make([]string, 0, len(exportColumns))
+	for name := range exportColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImportSize = 2 << 20 // 2 MB
+	maxImportRows = 5000
+)
+
+// importSummary counts the rows of an import by outcome.
+type importSummary struct {
+	Rows      int `json:"rows"`
+	Added     int `json:"added"`
+	Duplicate int `json:"duplicate"`
+	Unknown   int `json:"unknown"`
+	Invalid   int `json:"invalid"`
+}
+
+type importResponse struct {
+	DryRun  bool                  `json:"dry_run"`
+	Summary importSummary         `json:"summary"`
+	Rows    []*database.ImportRow `json:"rows"`
+}
+
+// parseImportCSV reads the rows of an attendee import. Each row names a
+// user by email address or numeric ID. Either the first column holds them,
+// or a header row names an "email" and/or "user_id" column; with both, the
+// ID is used when it is filled in. Rows that name nobody come back marked
+// invalid.
+func parseImportCSV(r io.Reader) ([]*database.ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	emailCol, idCol := 0, 0
+	header := true
+	var rows []*database.ImportRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		if header {
+			header = false
+			emailCol, idCol = -1, -1
+			// Spreadsheet applications often start UTF-8 files with a byte
+			// order mark
+			for i, cell := range record {
+				switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff"))) {
+				case "email", "e-mail", "email address":
+					emailCol = i
+				case "user_id", "userid", "id":
+					idCol = i
+				}
+			}
+			if emailCol >= 0 || idCol >= 0 {
+				continue
+			}
+			// No header; the first row is data
+			emailCol, idCol = 0, 0
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("an import can have at most %d rows", maxImportRows)
+		}
+		rows = append(rows, importRow(line, record, emailCol, idCol))
+	}
+	return rows, nil
+}
+
+// importRow makes the row on line from record's email and ID columns.
+func importRow(line int, record []string, emailCol, idCol int) *database.ImportRow {
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := &database.ImportRow{Line: line, Value: cell(idCol)}
+	if row.Value == "" {
+		row.Value = cell(emailCol)
+	}
+
+	if id, err := strconv.Atoi(row.Value); err == nil {
+		if id > 0 {
+			row.UserId = id
+			return row
+		}
+	} else if addr, err := mail.ParseAddress(row.Value); err == nil && addr.Address == row.Value {
+		row.Email = row.Value
+		return row
+	}
+
+	row.Result = database.ImportInvalid
+	if row.Value == "" {
+		row.Error = "no email address or user ID"
+	} else {
+		row.Error = "not an email address or user ID"
+	}
+	return row
+}
+
+// importAttendees handles POST /events/:id/attendees/import. The CSV comes
+// as the "file" field of a multipart form or as the request body. Valid
+// rows are added in one transaction; with dry_run=true nothing is written
+// and the report shows what would happen.
+//
+// @Summary Import attendees from CSV
+// @Description Add many people at once from a CSV of email addresses or user IDs, with a report for every row. Use dry_run=true to check the file first.
+// @Tags Attendees
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param file formData file false "CSV file, unless sent as the body"
+// @Param dry_run query bool false "Validate without adding anyone"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Success 200 {object} importResponse "Summary and per-row results"
+// @Failure 400 {object} gin.H "Unreadable or too large CSV"
+// @Failure 403 {object} gin.H "Not allowed to manage attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/attendees/import [post]
+func (app *application) importAttendees(c *gin.Context) {
+	dryRun := false
+	if value, found := c.GetQuery("dry_run"); found {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
+		return
+	}
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, err := parseImportCSV(body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("the file must be at most %d MB", maxImportSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid CSV: " + err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the file has no rows"})
+		return
+	}
+
+	if err := app.models.Attendees.Import(c.Request.Context(), event.Id, occurrence, rows, dryRun); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import attendees"})
+		return
+	}
+
+	response := importResponse{DryRun: dryRun, Rows: rows}
+	response.Summary.Rows = len(rows)
+	for _, row := range rows {
+		switch row.Result {
+		case database.ImportAdded:
+			response.Summary.Added++
+		case database.ImportDuplicate:
+			response.Summary.Duplicate++
+		case database.ImportUnknown:
+			response.Summary.Unknown++
+		case database.ImportInvalid:
+			response.Summary.Invalid++
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
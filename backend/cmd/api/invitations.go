@@ -0,0 +1,320 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/mailer"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createInvitationRequest creates an email invitation when Email is set and
+// a shareable link otherwise. MaxUses only applies to links; email
+// invitations work once.
+type createInvitationRequest struct {
+	Email     string     `json:"email" binding:"omitempty,email"`
+	MaxUses   *int       `json:"max_uses" binding:"omitempty,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// invitationResponse is what invitees see when they open a link: enough to
+// decide, without requiring an account.
+type invitationResponse struct {
+	Event     invitationEvent `json:"event"`
+	Email     *string         `json:"email,omitempty"`
+	Valid     bool            `json:"valid"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+}
+
+type invitationEvent struct {
+	Id            int       `json:"id"`
+	Name          string    `json:"name"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	Timezone      string    `json:"timezone"`
+	StartsAtLocal time.Time `json:"starts_at_local"`
+	EndsAtLocal   time.Time `json:"ends_at_local"`
+	Location      string    `json:"location"`
+}
+
+func (app *application) invitationURL(token string) string {
+	return fmt.Sprintf("%s/invitations/%s", app.frontendURL, url.PathEscape(token))
+}
+
+// createInvitation handles POST /events/:id/invitations.
+// Email invitations are mailed and their token is not returned; link
+// invitations return the token and URL once.
+//
+// @Summary Invite people to an event
+// @Description Invite someone by email (they need no account yet) or create a shareable link with optional max uses and expiry
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param body body createInvitationRequest true "Email, or link limits"
+// @Success 201 {object} gin.H "Invitation, plus token and url for links"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 403 {object} gin.H "Not allowed to manage attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/invitations [post]
+func (app *application) createInvitation(c *gin.Context) {
+	var input createInvitationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
+		return
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	invitation := database.Invitation{
+		EventId:   event.Id,
+		CreatedBy: user.Id,
+		TokenHash: hashToken(token),
+		MaxUses:   input.MaxUses,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if input.Email != "" {
+		one := 1
+		invitation.Email = &input.Email
+		invitation.MaxUses = &one
+	}
+
+	if err := app.models.Invitations.Insert(&invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	if invitation.Email == nil {
+		c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "token": token, "url": app.invitationURL(token)})
+		return
+	}
+
+	app.sendMail(mailer.Message{
+		To:      input.Email,
+		Subject: fmt.Sprintf("%s invited you to %s", user.Name, event.Name),
+		Body: fmt.Sprintf("Hi,\n\n%s invited you to \"%s\" on %s at %s.\n\nAccept or decline here (you can create an account on the way if you don't have one):\n\n%s\n",
+			user.Name, event.Name, event.StartsAtLocal.Format("Monday, 2 January 2006 15:04 MST"), event.Location, app.invitationURL(token)),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation})
+}
+
+// listInvitations handles GET /events/:id/invitations.
+//
+// @Summary List invitations
+// @Description List an event's invitations and how often they were used
+// @Tags Invitations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {array} database.Invitation "Invitations"
+// @Failure 403 {object} gin.H "Not allowed to manage attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/invitations [get]
+func (app *application) listInvitations(c *gin.Context) {
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
+		return
+	}
+
+	invitations, err := app.models.Invitations.GetByEvent(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// revokeInvitation handles DELETE /events/:id/invitations/:invitationId.
+// People who already accepted keep their RSVP.
+//
+// @Summary Revoke an invitation
+// @Description Stop an invitation or link from working
+// @Tags Invitations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param invitationId path int true "Invitation ID"
+// @Success 204 "Invitation revoked"
+// @Failure 400 {object} gin.H "Invalid invitation ID"
+// @Failure 403 {object} gin.H "Not allowed to manage attendees"
+// @Failure 404 {object} gin.H "Event or invitation not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/invitations/{invitationId} [delete]
+func (app *application) revokeInvitation(c *gin.Context) {
+	invitationId, err := strconv.Atoi(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
+		return
+	}
+
+	if err := app.models.Invitations.Revoke(c.Request.Context(), event.Id, invitationId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getInvitation handles GET /invitations/:token. It needs no account so the
+// landing page can show what the invitation is for.
+//
+// @Summary Look at an invitation
+// @Description Show the event an invitation is for and whether it can still be used
+// @Tags Invitations
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Success 200 {object} invitationResponse "Invitation"
+// @Failure 404 {object} gin.H "Invitation not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/invitations/{token} [get]
+func (app *application) getInvitation(c *gin.Context) {
+	invitation, err := app.models.Invitations.GetByHash(hashToken(c.Param("token")))
+	if err != nil {
+		app.invitationFailed(c, err)
+		return
+	}
+
+	event, err := app.models.Events.Get(invitation.EventId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return
+	}
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitationResponse{
+		Event: invitationEvent{
+			Id:            event.Id,
+			Name:          event.Name,
+			StartsAt:      event.StartsAt,
+			EndsAt:        event.EndsAt,
+			Timezone:      event.Timezone,
+			StartsAtLocal: event.StartsAtLocal,
+			EndsAtLocal:   event.EndsAtLocal,
+			Location:      event.Location,
+		},
+		Email:     invitation.Email,
+		Valid:     invitation.Usable(time.Now()),
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+// acceptInvitation handles POST /invitations/:token/accept. The user is
+// RSVPed as going, or waitlisted if the event is full.
+//
+// @Summary Accept an invitation
+// @Description Join the event the invitation is for
+// @Tags Invitations
+// @Produce json
+// @Security BearerAuth
+// @Param token path string true "Invitation token"
+// @Success 200 {object} database.Attendee "RSVP"
+// @Failure 403 {object} gin.H "Invitation sent to another email address"
+// @Failure 404 {object} gin.H "Invitation not found"
+// @Failure 410 {object} gin.H "Invitation expired, used up, declined or revoked"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/invitations/{token}/accept [post]
+func (app *application) acceptInvitation(c *gin.Context) {
+	user := app.getUserFromContext(c)
+	_, attendee, err := app.models.Invitations.Accept(c.Request.Context(), hashToken(c.Param("token")), user)
+	if err != nil {
+		app.invitationFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attendee)
+}
+
+// declineInvitation handles POST /invitations/:token/decline. It works
+// without an account so email invitees can say no without signing up; when
+// signed in, the user's RSVP is recorded as declined as well, so API keys
+// need the read-write scope.
+//
+// @Summary Decline an invitation
+// @Description Turn an invitation down
+// @Tags Invitations
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Success 200 {object} gin.H "Invitation declined"
+// @Failure 403 {object} gin.H "Invitation sent to another email address, or read-only API key"
+// @Failure 404 {object} gin.H "Invitation not found"
+// @Failure 410 {object} gin.H "Invitation expired, used up or revoked"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/invitations/{token}/decline [post]
+func (app *application) declineInvitation(c *gin.Context) {
+	user := app.getUserFromContext(c)
+	invitation, promoted, err := app.models.Invitations.Decline(c.Request.Context(), hashToken(c.Param("token")), user)
+	if err != nil {
+		app.invitationFailed(c, err)
+		return
+	}
+
+	if len(promoted) > 0 {
+		if event, err := app.models.Events.Get(invitation.EventId); err == nil && event != nil {
+			app.notifyPromoted(event, promoted)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+func (app *application) invitationFailed(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, database.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+	case errors.Is(err, database.ErrInvitationUnavailable):
+		c.JSON(http.StatusGone, gin.H{"error": "This invitation is no longer valid"})
+	case errors.Is(err, database.ErrInvitationForOther):
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation was sent to another email address"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process invitation"})
+	}
+}
+
+// acceptInvitationOnSignup redeems the invitation a new user registered
+// through. Failures do not stop the registration; they are only logged.
+func (app *application) acceptInvitationOnSignup(c *gin.Context, token string, user *database.User) *database.Attendee {
+	_, attendee, err := app.models.Invitations.Accept(c.Request.Context(), hashToken(token), user)
+	if err != nil {
+		log.Printf("failed to accept invitation for new user %d: %v", user.Id, err)
+		return nil
+	}
+	return attendee
+}
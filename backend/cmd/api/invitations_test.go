@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"testing"
+)
+
+func TestDeclineInvitation(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+	event := newTestEvent(t, app, 0, "")
+	ann := newTestUser(t, app, "ann")
+	bob := newTestUser(t, app, "bob")
+	if err := app.models.APIKeys.Insert(&database.APIKey{UserId: ann.Id, Name: "ci", Prefix: "evk_abc", KeyHash: hashToken("evk_abc"), Scope: database.APIKeyScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	for token, email := range map[string]string{"for-ann": "Ann@Example.com", "for-cat": "cat@example.com"} {
+		if err := app.models.Invitations.Insert(&database.Invitation{EventId: event.Id, CreatedBy: event.OwnerId, Email: &email, TokenHash: hashToken(token)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{"someone else's invitation", "for-ann", bearer(t, app, bob), http.StatusForbidden},
+		{"read-only API key", "for-ann", "ApiKey evk_abc", http.StatusForbidden},
+		{"anonymous", "for-cat", "", http.StatusOK},
+		{"invited account", "for-ann", bearer(t, app, ann), http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/invitations/"+tt.token+"/decline", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	if rsvp, err := app.models.Attendees.GetByEventAndAttendee(event.Id, "", bob.Id); err != nil || rsvp != nil {
+		t.Errorf("bob's RSVP = %+v, %v; want none", rsvp, err)
+	}
+	if rsvp, err := app.models.Attendees.GetByEventAndAttendee(event.Id, "", ann.Id); err != nil || rsvp == nil || rsvp.Status != database.AttendeeDeclined {
+		t.Errorf("ann's RSVP = %+v, %v; want declined", rsvp, err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"rest-api-in-gin/internal/loginlimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// emailLoginPolicy protects a single account: a handful of typos are free,
+	// after that every failure doubles the lockout.
+	emailLoginPolicy = loginlimit.Policy{
+		FreeAttempts: 5,
+		BaseLockout:  30 * time.Second,
+		MaxLockout:   time.Hour,
+		Window:       24 * time.Hour,
+	}
+
+	// ipLoginPolicy is looser because offices and mobile carriers put many
+	// users behind one address, but it stops one client spraying many accounts.
+	ipLoginPolicy = loginlimit.Policy{
+		FreeAttempts: 20,
+		BaseLockout:  time.Minute,
+		MaxLockout:   time.Hour,
+		Window:       24 * time.Hour,
+	}
+)
+
+// loginLimitKeys returns the limiter keys for an attempt on email from the
+// requesting client.
+func loginLimitKeys(c *gin.Context, email string) (emailKey, ipKey string) {
+	return "email:" + strings.ToLower(strings.TrimSpace(email)), "ip:" + c.ClientIP()
+}
+
+// checkLoginLockout responds with 429 and Retry-After when any of the keys is
+// locked. It returns false when the request was rejected.
+func (app *application) checkLoginLockout(c *gin.Context, keys ...string) bool {
+	wait, err := app.loginLimiter.Check(c.Request.Context(), keys...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts. Please try again later."})
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed attempt against both keys and writes an
+// audit entry for every key the failure locks.
+func (app *application) recordLoginFailure(c *gin.Context, email, emailKey, ipKey string, userId *int) error {
+	for _, limit := range []struct {
+		key    string
+		policy loginlimit.Policy
+	}{
+		{emailKey, emailLoginPolicy},
+		{ipKey, ipLoginPolicy},
+	} {
+		lockout, err := app.loginLimiter.Failure(c.Request.Context(), limit.key, limit.policy)
+		if err != nil {
+			return err
+		}
+		if lockout > 0 {
+			app.recordAudit(c, "login.lockout", userId, map[string]any{
+				"key":             limit.key,
+				"email":           email,
+				"lockout_seconds": int(lockout.Seconds()),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/loginlimit"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginLockout(t *testing.T) {
+	app := newTestApp(t)
+	app.loginLimiter = loginlimit.New(&app.models.LoginAttempts)
+	handler := app.routes()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(&database.User{Email: "ann@example.com", Name: "Ann", Password: string(hash)}); err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(loginRequest{Email: email, Password: password})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 1; i <= emailLoginPolicy.FreeAttempts; i++ {
+		if rec := login("ann@example.com", "wrong-password"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401: %s", i, rec.Code, rec.Body)
+		}
+	}
+	// A success within the free attempts forgets them
+	if rec := login("ann@example.com", "password123"); rec.Code != http.StatusOK {
+		t.Fatalf("correct password: status %d: %s", rec.Code, rec.Body)
+	}
+
+	for i := 0; i <= emailLoginPolicy.FreeAttempts; i++ {
+		login("Ann@Example.com", "wrong-password")
+	}
+	rec := login("ann@example.com", "password123")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked account: status %d, want 429: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("Retry-After = %q, want 30", rec.Header().Get("Retry-After"))
+	}
+
+	// Other accounts are not affected by the lockout
+	if rec := login("bob@example.com", "wrong-password"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("other account: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+	"rest-api-in-gin/internal/env"
+	"rest-api-in-gin/internal/mailer"
+	"time"
+)
+
+// newMailer picks the mail backend from MAILER: "smtp" sends through
+// SMTP_HOST/SMTP_PORT, anything else writes messages to MAIL_LOG_FILE (or the
+// server log when unset) so the API works offline.
+func newMailer() (mailer.Mailer, error) {
+	if env.GetEnvString("MAILER", "log") == "smtp" {
+		return &mailer.SMTPMailer{
+			Host:     env.GetEnvString("SMTP_HOST", "localhost"),
+			Port:     env.GetEnvInt("SMTP_PORT", 587),
+			Username: env.GetEnvString("SMTP_USERNAME", ""),
+			Password: env.GetEnvString("SMTP_PASSWORD", ""),
+			From:     env.GetEnvString("MAIL_FROM", "no-reply@localhost"),
+		}, nil
+	}
+	return mailer.NewLogMailer(env.GetEnvString("MAIL_LOG_FILE", ""))
+}
+
+// sendMail delivers msg in the background so a slow relay never holds up the
+// request. Failures are logged; callers treat email as best effort.
+func (app *application) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := app.mailer.Send(ctx, msg); err != nil {
+			log.Printf("failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
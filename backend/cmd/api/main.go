@@ -2,13 +2,24 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
 	_ "rest-api-in-gin/docs"
 	"rest-api-in-gin/internal/database"
 	"rest-api-in-gin/internal/env"
+	"rest-api-in-gin/internal/jwtkeys"
+	"rest-api-in-gin/internal/loginlimit"
+	"rest-api-in-gin/internal/mailer"
+	"rest-api-in-gin/internal/oidc"
+	"rest-api-in-gin/internal/tickets"
+	"rest-api-in-gin/internal/totp"
+	"strings"
+	"time"
+	_ "time/tzdata" // event timezones must resolve even without system zoneinfo
 
+	"github.com/gin-gonic/gin"
 	_ "github.com/joho/godotenv/autoload"
 	_ "modernc.org/sqlite"
 )
@@ -21,11 +32,33 @@ import (
 // @name Authorization
 // @description Enter your bearer token in the format **Bearer &lt;token&gt;**
 
+// defaultJWTSecret is only meant for local development; the server refuses to
+// sign with it in release mode.
+const defaultJWTSecret = "some-secret-123456"
+
+// defaultTicketSecret signs ticket codes in local development only, like
+// defaultJWTSecret.
+const defaultTicketSecret = "ticket-secret-123456"
+
+// defaultTOTPKey encrypts TOTP secrets in local development only.
+const defaultTOTPKey = "totp-key-123456"
+
 type application struct {
-	port      int
-	jwtSecret string
-	uploadDir string
-	models    database.Models
+	port                     int
+	keys                     *jwtkeys.KeySet
+	accessTokenTTL           time.Duration
+	refreshTokenTTL          time.Duration
+	passwordResetTTL         time.Duration
+	uploadDir                string
+	frontendURL              string
+	requireEmailVerification bool
+	totpIssuer               string
+	mailer                   mailer.Mailer
+	loginLimiter             *loginlimit.Limiter
+	oidcProviders            map[string]*oidc.Provider
+	tickets                  *tickets.Signer
+	totpSecrets              *totp.SecretBox
+	models                   database.Models
 }
 
 func main() {
@@ -34,7 +67,10 @@ func main() {
 		log.Fatal(err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	// Wait for locks instead of failing with SQLITE_BUSY, and take the write
+	// lock when a transaction starts so read-then-write transactions
+	// (refresh token rotation, RSVPs against a capacity) are serialized.
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -45,11 +81,49 @@ func main() {
 	if err := ensureDir(uploadDir); err != nil {
 		log.Fatal(err)
 	}
+	keys, err := loadKeySet()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mail, err := newMailer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	oidcProviders, err := loadOIDCProviders()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Changing the secret invalidates every ticket already handed out
+	ticketSecret := env.GetEnvString("TICKET_SECRET", defaultTicketSecret)
+	if ticketSecret == defaultTicketSecret && gin.Mode() == gin.ReleaseMode {
+		log.Fatal("refusing to start in release mode with the default ticket secret: set TICKET_SECRET")
+	}
+
+	// Changing the key turns off TOTP for everyone; recovery codes still work
+	totpKey := env.GetEnvString("TOTP_ENCRYPTION_KEY", defaultTOTPKey)
+	if totpKey == defaultTOTPKey && gin.Mode() == gin.ReleaseMode {
+		log.Fatal("refusing to start in release mode with the default TOTP key: set TOTP_ENCRYPTION_KEY")
+	}
+
 	app := &application{
-		port:      env.GetEnvInt("PORT", 8080),
-		jwtSecret: env.GetEnvString("JWT_SECRET", "some-secret-123456"),
-		uploadDir: uploadDir,
-		models:    models,
+		port:                     env.GetEnvInt("PORT", 8080),
+		keys:                     keys,
+		accessTokenTTL:           env.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		refreshTokenTTL:          env.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		passwordResetTTL:         env.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		uploadDir:                uploadDir,
+		frontendURL:              strings.TrimRight(env.GetEnvString("FRONTEND_URL", "http://localhost:3000"), "/"),
+		requireEmailVerification: env.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", true),
+		totpIssuer:               env.GetEnvString("TOTP_ISSUER", "Event Management API"),
+		mailer:                   mail,
+		loginLimiter:             newLoginLimiter(models),
+		oidcProviders:            oidcProviders,
+		tickets:                  tickets.NewSigner(ticketSecret),
+		totpSecrets:              totp.NewSecretBox(totpKey),
+		models:                   models,
 	}
 
 	if err := app.serve(); err != nil {
@@ -57,6 +131,52 @@ func main() {
 	}
 }
 
+// loadKeySet builds the JWT key set from the environment.
+//
+// JWT_SIGNING_KEY_FILE points at an RSA or Ed25519 private key in PEM format;
+// tokens are then signed with RS256 or EdDSA. JWT_VERIFICATION_KEY_FILES is a
+// comma-separated list of extra public (or private) key files that are still
+// accepted, which is how a previous key stays valid during rotation. Without
+// a signing key file the server falls back to HS256 with JWT_SECRET.
+func loadKeySet() (*jwtkeys.KeySet, error) {
+	var verify []*jwtkeys.Key
+	for _, path := range strings.Split(env.GetEnvString("JWT_VERIFICATION_KEY_FILES", ""), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := jwtkeys.LoadPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verify = append(verify, key)
+	}
+
+	if path := env.GetEnvString("JWT_SIGNING_KEY_FILE", ""); path != "" {
+		signing, err := jwtkeys.LoadPrivateKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return jwtkeys.NewKeySet(signing, verify...)
+	}
+
+	secret := env.GetEnvString("JWT_SECRET", defaultJWTSecret)
+	if secret == defaultJWTSecret && gin.Mode() == gin.ReleaseMode {
+		return nil, errors.New("refusing to start in release mode with the default JWT secret: set JWT_SECRET or JWT_SIGNING_KEY_FILE")
+	}
+	return jwtkeys.NewKeySet(jwtkeys.NewHMACKey(secret), verify...)
+}
+
+// newLoginLimiter selects where failed login attempts are tracked:
+// LOGIN_LIMIT_STORE=memory keeps them in process (single node, lost on
+// restart); the default "sqlite" keeps them in the database.
+func newLoginLimiter(models database.Models) *loginlimit.Limiter {
+	if env.GetEnvString("LOGIN_LIMIT_STORE", "sqlite") == "memory" {
+		return loginlimit.New(loginlimit.NewMemoryStore())
+	}
+	return loginlimit.New(&models.LoginAttempts)
+}
+
 func ensureDir(path string) error {
 	if path == "" || path == "." {
 		return nil
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/jwtkeys"
+	"rest-api-in-gin/internal/loginlimit"
+	"rest-api-in-gin/internal/mailer"
+	"rest-api-in-gin/internal/tickets"
+	"rest-api-in-gin/internal/totp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// newTestApp returns an application backed by a fresh, fully migrated
+// database in a temporary directory. Mail goes to a file there.
+func newTestApp(t *testing.T) *application {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "data.db")+"?_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	instance, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := (&file.File{}).Open("../migrate/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithInstance("file", source, "sqlite", instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := jwtkeys.NewKeySet(jwtkeys.NewHMACKey("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail, err := mailer.NewLogMailer(filepath.Join(dir, "mail.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := database.NewModels(db)
+	return &application{
+		keys:             keys,
+		accessTokenTTL:   15 * time.Minute,
+		refreshTokenTTL:  24 * time.Hour,
+		passwordResetTTL: time.Hour,
+		uploadDir:        filepath.Join(dir, "uploads"),
+		frontendURL:      "http://localhost:3000",
+		totpIssuer:       "Test",
+		mailer:           mail,
+		loginLimiter:     loginlimit.New(loginlimit.NewMemoryStore()),
+		tickets:          tickets.NewSigner("test-ticket-secret"),
+		totpSecrets:      totp.NewSecretBox("test-totp-key"),
+		models:           models,
+	}
+}
+
+// bearer signs user in with a new session and returns the Authorization
+// header value for it.
+func bearer(t *testing.T, app *application, user *database.User) string {
+	t.Helper()
+	session := database.Session{UserId: user.Id}
+	if err := app.models.Sessions.Insert(&session); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.newAccessToken(user.Id, session.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
@@ -14,14 +15,17 @@ import (
 // available to subsequent handlers via the Gin context.
 //
 // The middleware expects the Authorization header in the format: "Bearer <jwt_token>"
-// and validates the token against the application's JWT secret.
+// and validates the token against the application's JWT key set. Personal API
+// keys are accepted as "ApiKey <key>" and handled by authenticateAPIKey; those
+// requests carry an "apiKey" instead of a "session" in the context.
 // Authentication flow:
 //   1. Extracts Authorization header from the request
 //   2. Validates Bearer token format
 //   3. Parses and validates the JWT token signature and expiration
-//   4. Extracts user ID from token claims
-//   5. Loads user data from database to verify user still exists
-//   6. Stores user object in Gin context for use by protected handlers
+//   4. Extracts user ID and session ID from token claims
+//   5. Loads user data from database to verify user still exists and is not disabled
+//   6. Verifies the session has not been revoked and records activity on it
+//   7. Stores user and session objects in Gin context for use by protected handlers
 //
 // On authentication failure:
 //   - Returns 401 Unauthorized with error message
@@ -29,6 +33,7 @@ import (
 //
 // On authentication success:
 //   - Sets "user" key in Gin context with database.User object
+//   - Sets "session" key in Gin context with database.Session object
 //   - Calls c.Next() to continue to the protected handler
 func (app *application) AuthMiddleware() gin.HandlerFunc{
 	return func(c *gin.Context){
@@ -41,6 +46,12 @@ func (app *application) AuthMiddleware() gin.HandlerFunc{
 			return
 		}
 
+		// Scripts authenticate with a personal API key instead of a JWT
+		if key, ok := strings.CutPrefix(authHeader, "ApiKey "); ok {
+			app.authenticateAPIKey(c, key)
+			return
+		}
+
 		// Extract the token part by removing the "Bearer " prefix
         // TrimPrefix only removes the prefix if it exists, otherwise returns original string
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
@@ -54,16 +65,10 @@ func (app *application) AuthMiddleware() gin.HandlerFunc{
 		}
 
 		// Parse and validate the JWT token
-        // The key function validates the signing method and returns the secret for verification
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error){
-			// Ensure the token uses HMAC signing method (HS256, HS384, HS512)
-            // This prevents algorithm substitution attacks
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok{
-				return nil, jwt.ErrSignatureInvalid
-			}
-			// Return the application's JWT secret for signature verification
-			return []byte(app.jwtSecret), nil
-		})
+		// The key set picks the verification key by the token's kid header and
+		// rejects tokens whose alg does not match that key, which prevents
+		// algorithm substitution attacks
+		token, err := jwt.Parse(tokenString, app.keys.Keyfunc)
 
 		// Check for parsing errors or invalid token (expired, malformed signature, etc.)
 		if err != nil || !token.Valid{
@@ -74,8 +79,10 @@ func (app *application) AuthMiddleware() gin.HandlerFunc{
 
 		// Extract claims from the validated token
         // Claims contain the payload data (user ID, expiration, etc.)
+		// Single-purpose tokens (email verification etc.) are signed with the same
+		// keys but must never be accepted as access tokens
 		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		if !ok || claims["purpose"] != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
@@ -84,7 +91,12 @@ func (app *application) AuthMiddleware() gin.HandlerFunc{
 		// Extract user ID from token claims
         // JWT stores numbers as float64, so we need to cast appropriately
         // This assumes the token was created with "userId" claim during login
-		userId := claims["userId"].(float64)
+		userId, ok := claims["userId"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
 
 		// Load the user from database to ensure they still exist and are active
         // This catches cases where user accounts have been deleted/disabled after token issuance
@@ -103,13 +115,62 @@ func (app *application) AuthMiddleware() gin.HandlerFunc{
             return
         }
 
+		// Disabling an account revokes its sessions, but check explicitly so
+		// the answer is clear
+		if user.Disabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			c.Abort()
+			return
+		}
+
+		// Every access token is bound to a session through the "sid" claim.
+		// Tokens without one predate session tracking and cannot be revoked,
+		// so they are rejected and the client has to log in again.
+		sid, ok := claims["sid"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		session, err := app.models.Sessions.Get(int(sid))
+		if err != nil || session == nil || session.UserId != user.Id || session.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Only write last-seen once a minute per session to keep request
+		// handling cheap; the value is informational.
+		if time.Since(session.LastSeenAt) > time.Minute {
+			if err := app.models.Sessions.Touch(session.Id); err == nil {
+				session.LastSeenAt = time.Now().UTC()
+			}
+		}
+
 		// Store the authenticated user in the Gin context
         // This makes the user object available to all subsequent handlers in the request chain
         // Handlers can retrieve it with: user := c.MustGet("user").(*database.User)
 		c.Set("user", user)
+		c.Set("session", session)
 
 		// Continue to the next handler in the middleware chain
         // This allows the protected route handler to execute
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// OptionalAuthMiddleware authenticates the request like AuthMiddleware when
+// it carries an Authorization header, a Bearer token or an API key alike,
+// and lets it through anonymously otherwise. Handlers behind it see an empty
+// user for anonymous requests.
+func (app *application) OptionalAuthMiddleware() gin.HandlerFunc {
+	authenticate := app.AuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
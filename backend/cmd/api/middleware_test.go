@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"testing"
+	"time"
+)
+
+func TestOptionalAuthAcceptsAPIKeys(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	owner := database.User{Email: "ann@example.com", Name: "Ann", Password: "x"}
+	if err := app.models.Users.Insert(&owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.APIKeys.Insert(&database.APIKey{UserId: owner.Id, Name: "ci", Prefix: "evk_abc", KeyHash: hashToken("evk_abc"), Scope: database.APIKeyScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	starts := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	event := database.Event{
+		OwnerId: owner.Id, Name: "Team dinner", Description: "Dinner for the team", Location: "Berlin",
+		Visibility: database.VisibilityPrivate, StartsAt: starts, EndsAt: starts.Add(2 * time.Hour),
+	}
+	if err := app.models.Events.Insert(&event); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		auth   string
+		status int
+	}{
+		{"event with key", "/api/v1/events/%d", "ApiKey evk_abc", http.StatusOK},
+		{"occurrences with key", "/api/v1/events/%d/occurrences", "ApiKey evk_abc", http.StatusOK},
+		{"event anonymously", "/api/v1/events/%d", "", http.StatusNotFound},
+		{"event with unknown key", "/api/v1/events/%d", "ApiKey evk_nope", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf(tt.path, event.Id), nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
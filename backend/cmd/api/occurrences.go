@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultOccurrenceWindow is how far ahead occurrences are listed when
+	// no end is given; maxOccurrenceWindow is the most that can be asked for.
+	defaultOccurrenceWindow = 90 * 24 * time.Hour
+	maxOccurrenceWindow     = 366 * 24 * time.Hour
+	maxOccurrences          = 500
+)
+
+// Scopes of an occurrence edit or cancellation.
+const (
+	scopeThis      = "this"
+	scopeFollowing = "following"
+	scopeAll       = "all"
+)
+
+// validateRecurrence checks the event's RRULE and stores it in canonical
+// form. It writes a 400 response and returns false when the rule is invalid.
+func validateRecurrence(c *gin.Context, event *database.Event) bool {
+	if err := checkRecurrence(event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// checkRecurrence is validateRecurrence without the response.
+func checkRecurrence(event *database.Event) error {
+	if event.RRule != nil && *event.RRule == "" {
+		event.RRule = nil
+	}
+	rule, err := event.Rule()
+	if err != nil {
+		return errors.New("Invalid rrule: " + err.Error())
+	}
+	if rule != nil {
+		if !rule.Repeats(event.StartsAt.In(event.TimeLocation())) {
+			return errors.New("Invalid rrule: no date after the start matches it")
+		}
+		text := rule.String()
+		event.RRule = &text
+	}
+	for i, t := range event.ExDates {
+		event.ExDates[i] = t.UTC()
+	}
+	return nil
+}
+
+// findOccurrence parses value as the original start of an occurrence of a
+// recurring event and checks that the occurrence exists. On failure it
+// writes the response and returns false.
+func (app *application) findOccurrence(c *gin.Context, event *database.Event, value string) (time.Time, *database.Occurrence, bool) {
+	if event.RRule == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This event does not repeat"})
+		return time.Time{}, nil, false
+	}
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "occurrence must be the RFC3339 start of an occurrence"})
+		return time.Time{}, nil, false
+	}
+
+	occurrence, err := app.models.Occurrences.Get(event, start)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve occurrence"})
+		return time.Time{}, nil, false
+	}
+	if occurrence == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Occurrence not found"})
+		return time.Time{}, nil, false
+	}
+	return start, occurrence, true
+}
+
+// occurrenceParam reads the optional occurrence query parameter that points
+// RSVPs at one occurrence of a recurring event. It returns "" for the whole
+// event. On failure it writes the response and returns false.
+func (app *application) occurrenceParam(c *gin.Context, event *database.Event) (string, bool) {
+	value := c.Query("occurrence")
+	if value == "" {
+		return "", true
+	}
+	start, _, ok := app.findOccurrence(c, event, value)
+	if !ok {
+		return "", false
+	}
+	return database.OccurrenceKey(start), true
+}
+
+// parseTimeParam reads a query parameter given as RFC3339 or YYYY-MM-DD.
+func parseTimeParam(c *gin.Context, name string, fallback time.Time) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be RFC3339 or YYYY-MM-DD"})
+	return time.Time{}, false
+}
+
+// listOccurrences handles GET /events/:id/occurrences. Recurring events are
+// expanded from their RRULE, leaving out cancelled occurrences and applying
+// changes made to single ones; one-off events have one occurrence.
+//
+// @Summary List occurrences of an event
+// @Description Expand a recurring event into its occurrences between from and to (at most 366 days apart)
+// @Tags Events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param from query string false "Start of the window, RFC3339 or YYYY-MM-DD (default now)"
+// @Param to query string false "End of the window, exclusive (default 90 days after from)"
+// @Param limit query int false "Maximum occurrences (default and max 500)"
+// @Success 200 {array} database.Occurrence "Occurrences, soonest first"
+// @Failure 400 {object} gin.H "Invalid window"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/occurrences [get]
+func (app *application) listOccurrences(c *gin.Context) {
+	from, ok := parseTimeParam(c, "from", time.Now())
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(c, "to", from.Add(defaultOccurrenceWindow))
+	if !ok {
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxOccurrenceWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from and at most 366 days later"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(maxOccurrences)))
+	if err != nil || limit < 1 || limit > maxOccurrences {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxOccurrences)})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
+		return
+	}
+
+	occurrences, err := app.models.Occurrences.List(event, from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve occurrences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, occurrences)
+}
+
+// updateOccurrence handles PUT /events/:id/occurrences/:occurrence. With
+// scope=this only that occurrence changes. With scope=following the series
+// is split: it ends before the occurrence and a new event, which is
+// returned, continues from it with the changes. With scope=all the whole
+// series changes; a new start moves every occurrence by the same amount.
+//
+// @Summary Edit an occurrence
+// @Description Change one occurrence of a recurring event, it and all following ones, or the whole series
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence path string true "Original RFC3339 start of the occurrence"
+// @Param scope query string false "this (default), following or all"
+// @Param body body database.OccurrenceChanges true "Fields to change"
+// @Success 200 {object} gin.H "The event the occurrence now belongs to, and the occurrence"
+// @Failure 400 {object} gin.H "Invalid request"
+// @Failure 403 {object} gin.H "Not allowed to update the event"
+// @Failure 404 {object} gin.H "Event or occurrence not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/occurrences/{occurrence} [put]
+func (app *application) updateOccurrence(c *gin.Context) {
+	scope := c.DefaultQuery("scope", scopeThis)
+	if scope != scopeThis && scope != scopeFollowing && scope != scopeAll {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be one of this, following, all"})
+		return
+	}
+
+	var changes database.OccurrenceChanges
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionUpdateEvent)
+	if event == nil {
+		return
+	}
+	start, _, ok := app.findOccurrence(c, event, c.Param("occurrence"))
+	if !ok {
+		return
+	}
+
+	// Changing every occurrence from the first one on is changing them all
+	if scope == scopeFollowing && start.Equal(event.StartsAt) {
+		scope = scopeAll
+	}
+
+	newStart := start
+	if changes.StartsAt != nil {
+		newStart = *changes.StartsAt
+	}
+
+	var err error
+	switch scope {
+	case scopeThis:
+		// A single occurrence keeps its original start as its key
+		newStart = start
+		err = app.models.Occurrences.Modify(c.Request.Context(), event, start, changes)
+	case scopeFollowing:
+		event, err = app.models.Occurrences.Split(c.Request.Context(), event, start, changes)
+	case scopeAll:
+		err = app.models.Occurrences.UpdateSeries(c.Request.Context(), event, start, changes)
+	}
+	if errors.Is(err, database.ErrEndBeforeStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update occurrence"})
+		return
+	}
+
+	occurrence, err := app.models.Occurrences.Get(event, newStart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve occurrence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": event, "occurrence": occurrence})
+}
+
+// cancelOccurrence handles DELETE /events/:id/occurrences/:occurrence.
+// scope=this cancels the occurrence, scope=following ends the series before
+// it, and scope=all deletes the whole event. RSVPs of cancelled occurrences
+// are kept with status cancelled.
+//
+// @Summary Cancel an occurrence
+// @Description Cancel one occurrence of a recurring event, it and all following ones, or the whole series
+// @Tags Events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence path string true "Original RFC3339 start of the occurrence"
+// @Param scope query string false "this (default), following or all"
+// @Success 204 "Occurrences cancelled"
+// @Failure 400 {object} gin.H "Invalid request"
+// @Failure 403 {object} gin.H "Not allowed to update or delete the event"
+// @Failure 404 {object} gin.H "Event or occurrence not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/occurrences/{occurrence} [delete]
+func (app *application) cancelOccurrence(c *gin.Context) {
+	scope := c.DefaultQuery("scope", scopeThis)
+	action := actionUpdateEvent
+	switch scope {
+	case scopeThis, scopeFollowing:
+	case scopeAll:
+		action = actionDeleteEvent
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be one of this, following, all"})
+		return
+	}
+
+	event := app.authorizeEvent(c, action)
+	if event == nil {
+		return
+	}
+	start, _, ok := app.findOccurrence(c, event, c.Param("occurrence"))
+	if !ok {
+		return
+	}
+
+	var err error
+	switch {
+	case scope == scopeAll:
+		err = app.models.Events.Delete(event.Id)
+	case scope == scopeFollowing && start.Equal(event.StartsAt):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This is the first occurrence; use scope=all to cancel the whole series"})
+		return
+	case scope == scopeFollowing:
+		err = app.models.Occurrences.End(c.Request.Context(), event, start)
+	default:
+		err = app.models.Occurrences.Cancel(c.Request.Context(), event, start)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel occurrence"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
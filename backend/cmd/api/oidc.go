@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/env"
+	"rest-api-in-gin/internal/oidc"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	oidcStatePurpose = "oidc_state"
+	oidcStateTTL     = 10 * time.Minute
+	oidcStateCookie  = "oidc_state"
+)
+
+// loadOIDCProviders reads the identity providers from the environment.
+// OIDC_PROVIDERS is a comma-separated list of names; each name then needs
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_REDIRECT_URL, and
+// optionally OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES.
+func loadOIDCProviders() (map[string]*oidc.Provider, error) {
+	providers := map[string]*oidc.Provider{}
+	for _, name := range strings.Split(env.GetEnvString("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg := oidc.Config{
+			Name:         name,
+			Issuer:       env.GetEnvString(prefix+"ISSUER", ""),
+			ClientID:     env.GetEnvString(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetEnvString(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  env.GetEnvString(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(strings.ReplaceAll(env.GetEnvString(prefix+"SCOPES", ""), ",", " ")),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC provider %q needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		providers[name] = oidc.NewProvider(cfg)
+	}
+	return providers, nil
+}
+
+// startOIDCLogin handles GET /auth/oidc/:provider/start.
+// It redirects the browser to the provider. State, nonce and the PKCE
+// verifier travel in a short-lived signed cookie so the callback can check
+// them without any server-side storage.
+//
+// @Summary Start OIDC login
+// @Description Redirect to an external OpenID Connect provider
+// @Tags Authentication
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the provider"
+// @Failure 404 {object} gin.H "Unknown provider"
+// @Failure 502 {object} gin.H "Provider unavailable"
+// @Router /api/v1/auth/oidc/{provider}/start [get]
+func (app *application) startOIDCLogin(c *gin.Context) {
+	provider, ok := app.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
+		return
+	}
+
+	state, err := oidc.RandomString(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Printf("oidc start for %s: %v", provider.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	cookie, err := app.signPurposeToken(oidcStatePurpose, oidcStateTTL, jwt.MapClaims{
+		"provider": provider.Name,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	// Lax so the cookie comes back on the provider's top-level redirect
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, int(oidcStateTTL.Seconds()), "/api/v1/auth/oidc/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// oidcCallback handles GET /auth/oidc/:provider/callback.
+// It exchanges the authorization code, verifies the ID token and signs the
+// user in, creating or linking a local account on first use. The response is
+// the same as for /auth/login.
+//
+// @Summary Complete OIDC login
+// @Description Exchange the provider's authorization code for API tokens
+// @Tags Authentication
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the start request"
+// @Success 200 {object} loginResponse "Login successful"
+// @Failure 400 {object} gin.H "Invalid state or provider error"
+// @Failure 401 {object} gin.H "Identity could not be verified"
+// @Failure 404 {object} gin.H "Unknown provider"
+// @Failure 409 {object} gin.H "Email belongs to an account that cannot be linked"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/oidc/{provider}/callback [get]
+func (app *application) oidcCallback(c *gin.Context) {
+	provider, ok := app.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
+		return
+	}
+
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/v1/auth/oidc/", "", c.Request.TLS != nil, true)
+
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provider returned an error", "provider_error": errCode, "description": c.Query("error_description")})
+		return
+	}
+
+	claims, err := app.parsePurposeToken(oidcStatePurpose, cookie)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login session expired; start again"})
+		return
+	}
+	state, _ := claims["state"].(string)
+	if claims["provider"] != provider.Name || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "State mismatch"})
+		return
+	}
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		log.Printf("oidc callback for %s: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not verify identity with provider"})
+		return
+	}
+
+	user, failure := app.resolveOIDCUser(c, provider.Name, identity)
+	if failure != nil {
+		c.JSON(failure.status, gin.H{"error": failure.msg})
+		return
+	}
+	if user.Disabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	// Second factors still apply to accounts that have one
+	if user.TwoFactorEnabledAt != nil {
+		challenge, err := app.newTwoFactorChallenge(user.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokens, err := app.issueTokenPair(c, user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// resolveOIDCUser finds the local account for an external identity. A known
+// identity maps straight to its user. Otherwise an account with the same
+// email is linked, but only when the provider verified that address, and
+// failing that a new account is created. Failures come with the HTTP status
+// to respond with and a message safe to show the client; internal errors
+// are logged.
+func (app *application) resolveOIDCUser(c *gin.Context, provider string, claims *oidc.Claims) (*database.User, *requestError) {
+	failed := func(err error) *requestError {
+		log.Printf("oidc sign-in with %s: %v", provider, err)
+		return &requestError{http.StatusInternalServerError, "Failed to sign in"}
+	}
+
+	linked, err := app.models.Identities.Get(provider, claims.Subject)
+	if err != nil {
+		return nil, failed(fmt.Errorf("look up identity: %w", err))
+	}
+	if linked != nil {
+		user, err := app.models.Users.GetUserByID(linked.UserId)
+		if err != nil {
+			return nil, failed(fmt.Errorf("load linked user %d: %w", linked.UserId, err))
+		}
+		if user == nil {
+			return nil, failed(fmt.Errorf("identity links to missing user %d", linked.UserId))
+		}
+		return user, nil
+	}
+
+	if claims.Email == "" {
+		return nil, &requestError{http.StatusBadRequest, "Provider did not share an email address"}
+	}
+
+	identity := database.UserIdentity{Provider: provider, Subject: claims.Subject, Email: claims.Email}
+
+	existing, err := app.models.Users.GetByEmail(claims.Email)
+	if err != nil {
+		return nil, failed(fmt.Errorf("look up user by email: %w", err))
+	}
+	if existing != nil {
+		// Linking on an unverified address would let anyone who can set that
+		// email at the provider take over the account
+		if !claims.EmailVerified {
+			return nil, &requestError{http.StatusConflict, "An account with this email already exists"}
+		}
+
+		identity.UserId = existing.Id
+		if err := app.models.Identities.Insert(&identity); err != nil {
+			return nil, failed(fmt.Errorf("link identity to user %d: %w", existing.Id, err))
+		}
+		if existing.EmailVerifiedAt == nil {
+			if _, err := app.models.Users.MarkEmailVerified(c.Request.Context(), existing.Id, existing.Email); err != nil {
+				return nil, failed(fmt.Errorf("mark email of user %d verified: %w", existing.Id, err))
+			}
+		}
+		app.recordAudit(c, "oidc.linked", &existing.Id, map[string]any{"provider": provider})
+		return existing, nil
+	}
+
+	// The account gets an unguessable password; the user can set a real one
+	// through the password reset flow if they ever want to log in directly
+	random, err := randomToken(32)
+	if err != nil {
+		return nil, failed(fmt.Errorf("generate password: %w", err))
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, failed(fmt.Errorf("hash password: %w", err))
+	}
+
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	user := database.User{Email: claims.Email, Name: name, Password: string(hash)}
+	if err := app.models.Identities.CreateUser(c.Request.Context(), &user, &identity, claims.EmailVerified); err != nil {
+		return nil, failed(fmt.Errorf("create user: %w", err))
+	}
+	if !claims.EmailVerified {
+		if err := app.sendVerificationEmail(&user); err != nil {
+			log.Printf("failed to create verification email for user %d: %v", user.Id, err)
+		}
+	}
+
+	app.recordAudit(c, "oidc.linked", &user.Id, map[string]any{"provider": provider, "created": true})
+	return &user, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/jwtkeys"
+	"rest-api-in-gin/internal/oidc"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// stubIdP is a minimal OpenID provider. It issues an ID token for whatever
+// identity the test sets, with the nonce of the last authorization request.
+type stubIdP struct {
+	*httptest.Server
+	keys *jwtkeys.KeySet
+
+	mu        sync.Mutex
+	identity  jwt.MapClaims
+	nonce     string
+	hits      map[string]int
+	lastGrant url.Values
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "idp.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwtkeys.LoadPrivateKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := jwtkeys.NewKeySet(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &stubIdP{keys: keys, hits: map[string]int{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.hit("discovery")
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.hit("jwks")
+		json.NewEncoder(w).Encode(idp.keys.JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.hit("token")
+		r.ParseForm()
+		idp.mu.Lock()
+		idp.lastGrant = r.PostForm
+		claims := jwt.MapClaims{
+			"iss":   idp.URL,
+			"aud":   "api",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": idp.nonce,
+		}
+		for k, v := range idp.identity {
+			claims[k] = v
+		}
+		idp.mu.Unlock()
+
+		token, err := idp.keys.Sign(claims)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": token, "token_type": "Bearer"})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *stubIdP) hit(endpoint string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.hits[endpoint]++
+}
+
+// signIn runs the whole browser flow against the app, with the IdP
+// vouching for identity, and returns the callback response.
+func (idp *stubIdP) signIn(t *testing.T, handler http.Handler, identity jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+	start := httptest.NewRecorder()
+	handler.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/stub/start", nil))
+	if start.Code != http.StatusFound {
+		t.Fatalf("start: status %d: %s", start.Code, start.Body)
+	}
+	location, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "api" {
+		t.Fatalf("start: unexpected authorization URL %s", location)
+	}
+
+	idp.mu.Lock()
+	idp.identity = identity
+	idp.nonce = query.Get("nonce")
+	idp.mu.Unlock()
+
+	callback := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/stub/callback?code=the-code&state="+url.QueryEscape(query.Get("state")), nil)
+	for _, cookie := range start.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, callback)
+	return rec
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	app := newTestApp(t)
+	idp := newStubIdP(t)
+	app.oidcProviders = map[string]*oidc.Provider{
+		"stub": oidc.NewProvider(oidc.Config{Name: "stub", Issuer: idp.URL, ClientID: "api", RedirectURL: "http://localhost/callback"}),
+	}
+	handler := app.routes()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := database.User{Email: "ann@example.com", Name: "Ann", Password: string(hash)}
+	if err := app.models.Users.Insert(&existing); err != nil {
+		t.Fatal(err)
+	}
+
+	userOf := func(subject string) int {
+		t.Helper()
+		identity, err := app.models.Identities.Get("stub", subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity == nil {
+			return 0
+		}
+		return identity.UserId
+	}
+
+	tests := []struct {
+		name     string
+		identity jwt.MapClaims
+		status   int
+		userId   func() int
+	}{
+		{
+			name:     "new identity creates an account",
+			identity: jwt.MapClaims{"sub": "bob-1", "email": "bob@example.com", "email_verified": true, "name": "Bob"},
+			status:   http.StatusOK,
+		},
+		{
+			name:     "known identity signs in again",
+			identity: jwt.MapClaims{"sub": "bob-1", "email": "bob@example.com", "email_verified": true},
+			status:   http.StatusOK,
+		},
+		{
+			name:     "unverified email does not link an existing account",
+			identity: jwt.MapClaims{"sub": "ann-1", "email": "ann@example.com", "email_verified": false},
+			status:   http.StatusConflict,
+			userId:   func() int { return 0 },
+		},
+		{
+			name:     "verified email links an existing account",
+			identity: jwt.MapClaims{"sub": "ann-2", "email": "ann@example.com", "email_verified": "true"},
+			status:   http.StatusOK,
+			userId:   func() int { return existing.Id },
+		},
+		{
+			name:     "no email",
+			identity: jwt.MapClaims{"sub": "carl-1"},
+			status:   http.StatusBadRequest,
+			userId:   func() int { return 0 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := idp.signIn(t, handler, tt.identity)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				var tokens loginResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil || tokens.Token == "" || tokens.RefreshToken == "" {
+					t.Fatalf("no tokens in %s", rec.Body)
+				}
+			}
+			if tt.userId != nil {
+				if got, want := userOf(tt.identity["sub"].(string)), tt.userId(); got != want {
+					t.Errorf("identity links to user %d, want %d", got, want)
+				}
+			}
+		})
+	}
+
+	bob := userOf("bob-1")
+	if bob == 0 || bob == existing.Id {
+		t.Errorf("bob-1 links to user %d, want a new account", bob)
+	}
+	if user, err := app.models.Users.GetByEmail("bob@example.com"); err != nil || user == nil || user.Id != bob || user.EmailVerifiedAt == nil {
+		t.Errorf("bob@example.com = %+v, %v; want verified user %d", user, err, bob)
+	}
+	if user, err := app.models.Users.GetUserByID(existing.Id); err != nil || user.EmailVerifiedAt == nil {
+		t.Errorf("linking did not mark ann@example.com verified: %+v, %v", user, err)
+	}
+
+	if idp.lastGrant.Get("code") != "the-code" || idp.lastGrant.Get("code_verifier") == "" {
+		t.Errorf("token request = %v, want the code and a PKCE verifier", idp.lastGrant)
+	}
+	// Metadata and keys are fetched once and cached
+	if idp.hits["discovery"] != 1 || idp.hits["jwks"] != 1 {
+		t.Errorf("discovery fetched %d times, JWKS %d times; want once each", idp.hits["discovery"], idp.hits["jwks"])
+	}
+}
+
+func TestOIDCCallbackRejectsBadState(t *testing.T) {
+	app := newTestApp(t)
+	idp := newStubIdP(t)
+	app.oidcProviders = map[string]*oidc.Provider{
+		"stub": oidc.NewProvider(oidc.Config{Name: "stub", Issuer: idp.URL, ClientID: "api", RedirectURL: "http://localhost/callback"}),
+	}
+	handler := app.routes()
+
+	start := httptest.NewRecorder()
+	handler.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/stub/start", nil))
+
+	callback := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/stub/callback?code=the-code&state=forged", nil)
+	for _, cookie := range start.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, callback)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body)
+	}
+	if idp.hits["token"] != 0 {
+		t.Errorf("token endpoint called %d times with a forged state", idp.hits["token"])
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/mailer"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// addOrganizerRequest names the user to add by email. Owners are made with
+// the transfer endpoint instead.
+type addOrganizerRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=editor check_in"`
+}
+
+type transferEventRequest struct {
+	UserId int `json:"userId" binding:"required"`
+}
+
+// listOrganizers handles GET /events/:id/organizers.
+//
+// @Summary List organizers
+// @Description List the team running an event
+// @Tags Events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {array} database.Organizer "Organizers"
+// @Failure 403 {object} gin.H "Not an organizer of this event"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/organizers [get]
+func (app *application) listOrganizers(c *gin.Context) {
+	event := app.authorizeEvent(c, actionViewOrganizers)
+	if event == nil {
+		return
+	}
+
+	organizers, err := app.models.Organizers.GetByEvent(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organizers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, organizers)
+}
+
+// addOrganizer handles POST /events/:id/organizers.
+// The user must already have an account; they are told by email. They are
+// added right away rather than invited to accept: only the owner can add
+// people, a role grants nothing beyond this event, and anyone added can
+// leave again with removeOrganizer.
+//
+// @Summary Add an organizer
+// @Description Give an existing user the editor or check-in role on an event (owner only)
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param body body addOrganizerRequest true "User email and role"
+// @Success 201 {object} gin.H "Organizer added"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 403 {object} gin.H "Only the owner can manage organizers"
+// @Failure 404 {object} gin.H "Event or user not found"
+// @Failure 409 {object} gin.H "User is already an organizer"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/organizers [post]
+func (app *application) addOrganizer(c *gin.Context) {
+	var input addOrganizerRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionManageOrganizers)
+	if event == nil {
+		return
+	}
+
+	invitee, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+	if invitee == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := app.models.Organizers.Add(c.Request.Context(), event.Id, invitee.Id, input.Role); err != nil {
+		if errors.Is(err, database.ErrOrganizerExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already an organizer"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add organizer"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	app.sendMail(mailer.Message{
+		To:      invitee.Email,
+		Subject: fmt.Sprintf("You are now helping run %s", event.Name),
+		Body: fmt.Sprintf("Hi %s,\n\n%s added you as %s for \"%s\".\n\nYou can find it under your events: %s/events/%d\n",
+			invitee.Name, user.Name, input.Role, event.Name, app.frontendURL, event.Id),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Organizer added", "eventId": event.Id, "userId": invitee.Id, "role": input.Role})
+}
+
+// removeOrganizer handles DELETE /events/:id/organizers/:userId.
+// Owners can remove anyone but themselves; other organizers can remove
+// themselves to leave the team.
+//
+// @Summary Remove an organizer
+// @Description Remove someone from an event's team, or leave it
+// @Tags Events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param userId path int true "User ID"
+// @Success 204 "Organizer removed"
+// @Failure 400 {object} gin.H "Invalid user ID or attempt to remove the owner"
+// @Failure 403 {object} gin.H "Only the owner can manage organizers"
+// @Failure 404 {object} gin.H "Event or organizer not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/organizers/{userId} [delete]
+func (app *application) removeOrganizer(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	action := actionManageOrganizers
+	if userId == app.getUserFromContext(c).Id {
+		action = actionViewEvent
+	}
+	event := app.authorizeEvent(c, action)
+	if event == nil {
+		return
+	}
+	if userId == event.OwnerId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner cannot be removed; transfer ownership first"})
+		return
+	}
+
+	if err := app.models.Organizers.Remove(c.Request.Context(), event.Id, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organizer not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove organizer"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// transferEvent handles POST /events/:id/transfer.
+// The new owner can be any existing user; the previous owner stays on as an
+// editor.
+//
+// @Summary Transfer event ownership
+// @Description Make another user the owner of an event (owner or admin only)
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param body body transferEventRequest true "New owner"
+// @Success 200 {object} database.Event "Updated event"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 403 {object} gin.H "Only the owner can transfer the event"
+// @Failure 404 {object} gin.H "Event or user not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/transfer [post]
+func (app *application) transferEvent(c *gin.Context) {
+	var input transferEventRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionTransferEvent)
+	if event == nil {
+		return
+	}
+	if input.UserId == event.OwnerId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already owns this event"})
+		return
+	}
+
+	newOwner, err := app.models.Users.GetUserByID(input.UserId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+	if newOwner == nil || newOwner.Disabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	previousOwner := event.OwnerId
+	if err := app.models.Organizers.TransferOwnership(c.Request.Context(), event, newOwner.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer event"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	app.recordAudit(c, "event.transferred", &user.Id, map[string]any{"event_id": event.Id, "from": previousOwner, "to": newOwner.Id})
+
+	c.Header("ETag", eventETag(event))
+	c.JSON(http.StatusOK, event)
+}
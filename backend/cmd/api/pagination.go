@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// readListParams reads the query parameters shared by list endpoints: limit,
+// cursor, sort and the named filters. paged reports whether the client sent
+// any of them; endpoints that predate pagination answer unpaged requests with
+// the bare array they always returned. On error it writes the response and
+// ok is false.
+func readListParams(c *gin.Context, filters []string) (params database.ListParams, paged, ok bool) {
+	params.Cursor = c.Query("cursor")
+	params.Sort = c.Query("sort")
+	paged = params.Cursor != "" || params.Sort != ""
+
+	for _, name := range filters {
+		if value, found := c.GetQuery(name); found {
+			if params.Filters == nil {
+				params.Filters = map[string]string{}
+			}
+			params.Filters[name] = value
+			paged = true
+		}
+	}
+
+	if value, found := c.GetQuery("limit"); found {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+			return params, paged, false
+		}
+		params.Limit = limit
+		paged = true
+	} else if paged {
+		params.Limit = defaultPageSize
+	}
+
+	return params, paged, true
+}
+
+// respondList writes page as a {items, next_cursor, total} envelope, or just
+// its items for unpaged requests.
+func respondList[T any](c *gin.Context, page *database.Page[T], paged bool) {
+	if !paged {
+		c.JSON(http.StatusOK, page.Items)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// listFailed answers an error from a list query: 400 for parameters the
+// list rejected, 500 otherwise.
+func listFailed(c *gin.Context, err error, what string) {
+	var paramErr *database.ListParamError
+	if errors.As(err, &paramErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": paramErr.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve " + what})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/mailer"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// forgotPasswordRequest identifies the account that wants a reset link.
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// resetPasswordRequest redeems a reset token for a new password.
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// forgotPassword handles POST /auth/password/forgot requests.
+// The response is always 202 so the endpoint can't be used to find out which
+// addresses have accounts. A reset link is only mailed when one exists.
+//
+// @Summary Request a password reset
+// @Description Email a one-time password reset link if the address belongs to an account
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body forgotPasswordRequest true "Account email"
+// @Success 202 {object} gin.H "Reset email sent if the account exists"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Router /api/v1/auth/password/forgot [post]
+func (app *application) forgotPassword(c *gin.Context) {
+	var input forgotPasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted := gin.H{"message": "If an account exists for that email, a reset link has been sent"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		log.Printf("password reset lookup failed: %v", err)
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		log.Printf("failed to generate password reset token: %v", err)
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	record := database.PasswordResetToken{
+		UserId:    user.Id,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(app.passwordResetTTL),
+	}
+	if err := app.models.PasswordResets.Insert(&record); err != nil {
+		log.Printf("failed to store password reset token for user %d: %v", user.Id, err)
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", app.frontendURL, url.QueryEscape(token))
+	app.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. If that was you, open the link below:\n\n%s\n\nThe link can be used once and expires in %s. If you didn't ask for this, you can ignore this email.\n",
+			user.Name, link, app.passwordResetTTL),
+	})
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// resetPassword handles POST /auth/password/reset requests.
+// A successful reset signs the user out of every session and deletes their
+// API keys.
+//
+// @Summary Reset password
+// @Description Set a new password using a token from the reset email
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body resetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} gin.H "Password updated"
+// @Failure 400 {object} gin.H "Invalid request body or invalid/expired token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/password/reset [post]
+func (app *application) resetPassword(c *gin.Context) {
+	var input resetPasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if _, err := app.models.PasswordResets.Consume(c.Request.Context(), hashToken(input.Token), hashed); err != nil {
+		if errors.Is(err, database.ErrPasswordResetTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated. Please log in with your new password."})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestResetPasswordRevokesCredentials(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	user := database.User{Email: "ann@example.com", Name: "Ann", Password: "x"}
+	if err := app.models.Users.Insert(&user); err != nil {
+		t.Fatal(err)
+	}
+	session := database.Session{UserId: user.Id}
+	if err := app.models.Sessions.Insert(&session); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.APIKeys.Insert(&database.APIKey{UserId: user.Id, Name: "ci", Prefix: "evk_abc", KeyHash: hashToken("evk_abc"), Scope: database.APIKeyScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	reset := database.PasswordResetToken{UserId: user.Id, TokenHash: hashToken("the-token"), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := app.models.PasswordResets.Insert(&reset); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(resetPasswordRequest{Token: "the-token", Password: "new-password"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password/reset", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	updated, err := app.models.Users.GetUserByID(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password")) != nil {
+		t.Error("password not changed")
+	}
+	if sessions, err := app.models.Sessions.GetActiveByUser(user.Id); err != nil || len(sessions) != 0 {
+		t.Errorf("active sessions = %d, %v; want none", len(sessions), err)
+	}
+	if key, err := app.models.APIKeys.GetByHash(hashToken("evk_abc")); err != nil || key != nil {
+		t.Errorf("API key = %+v, %v; want it deleted", key, err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registrationFormRequest is a whole registration form; it can have at most
+// 50 fields.
+type registrationFormRequest struct {
+	Fields []*database.RegistrationField `json:"fields" binding:"max=50,dive"`
+}
+
+// answersRequest is the optional body of POST /events/:id/attendees/:userId.
+type answersRequest struct {
+	Answers map[string]json.RawMessage `json:"answers"`
+}
+
+// checkAnswers validates answers submitted for the user's RSVP to event
+// against its registration form. With required set, every required question
+// must be answered now or have been before. It writes a 400 response and
+// returns false when the answers do not fit.
+func (app *application) checkAnswers(c *gin.Context, event *database.Event, occurrence string, userId int, submitted map[string]json.RawMessage, required bool) (database.Answers, bool) {
+	fields, err := app.models.Registration.Fields(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registration form"})
+		return nil, false
+	}
+	if len(fields) == 0 && len(submitted) == 0 {
+		return nil, true
+	}
+
+	existing := database.Answers{}
+	if required {
+		attendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, occurrence, userId)
+		if err == nil && attendee != nil {
+			existing, err = app.models.Registration.Answers(attendee.Id)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registration answers"})
+			return nil, false
+		}
+	}
+
+	answers, err := database.CheckAnswers(fields, submitted, existing, required)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return answers, true
+}
+
+// getRegistrationForm handles GET /events/:id/registration-form. Anyone who
+// can see the event can see the questions, so they can answer them when
+// they RSVP.
+//
+// @Summary Get an event's registration form
+// @Description The questions asked when people sign up for the event
+// @Tags Registration
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {object} registrationFormRequest "Registration form"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/registration-form [get]
+func (app *application) getRegistrationForm(c *gin.Context) {
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
+		return
+	}
+
+	fields, err := app.models.Registration.Fields(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registration form"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fields": fields})
+}
+
+// setRegistrationForm handles PUT /events/:id/registration-form. The form
+// is replaced as a whole. Pass a field's id to keep it and its answers;
+// changing its type drops them.
+//
+// @Summary Set an event's registration form
+// @Description Define the questions asked at sign-up: text, number, boolean, single_choice or multi_choice fields, optionally required
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param body body registrationFormRequest true "Fields, in order"
+// @Success 200 {object} registrationFormRequest "Registration form"
+// @Failure 400 {object} gin.H "Invalid form"
+// @Failure 403 {object} gin.H "Not allowed to update the event"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/registration-form [put]
+func (app *application) setRegistrationForm(c *gin.Context) {
+	var input registrationFormRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, f := range input.Fields {
+		if err := f.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	event := app.authorizeEvent(c, actionUpdateEvent)
+	if event == nil {
+		return
+	}
+
+	fields, err := app.models.Registration.SetFields(c.Request.Context(), event.Id, input.Fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save registration form"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fields": fields})
+}
+
+// listRegistrationAnswers handles GET /events/:id/registration-answers: the
+// answers of everyone going or waitlisted, one entry per person.
+//
+// @Summary List registration answers
+// @Description Everyone's answers to the registration form, per attendee
+// @Tags Registration
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Success 200 {array} database.AttendeeAnswers "Answers by attendee, keyed by field id"
+// @Failure 403 {object} gin.H "Not allowed to manage attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/registration-answers [get]
+func (app *application) listRegistrationAnswers(c *gin.Context) {
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
+		return
+	}
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	answers, err := app.models.Registration.EventAnswers(event.Id, occurrence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registration answers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, answers)
+}
+
+// summarizeRegistrationAnswers handles
+// GET /events/:id/registration-answers/summary: per question, how often
+// each answer was given, or min, max, sum and average for numbers.
+//
+// @Summary Summarize registration answers
+// @Description Answers to the registration form added up per question, e.g. how many of each T-shirt size
+// @Tags Registration
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Success 200 {array} database.FieldSummary "One summary per field"
+// @Failure 403 {object} gin.H "Not allowed to manage attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/registration-answers/summary [get]
+func (app *application) summarizeRegistrationAnswers(c *gin.Context) {
+	event := app.authorizeEvent(c, actionManageAttendees)
+	if event == nil {
+		return
+	}
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	fields, err := app.models.Registration.Fields(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registration form"})
+		return
+	}
+	answers, err := app.models.Registration.EventAnswers(event.Id, occurrence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registration answers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"respondents": len(answers), "fields": database.Summarize(fields, answers)})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"rest-api-in-gin/internal/database"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -17,8 +18,9 @@ func (app *application) routes() http.Handler {
 
 	g.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:3001"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -26,6 +28,9 @@ func (app *application) routes() http.Handler {
 	g.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Welcome to the Event Management API", "docs": "/swagger/index.html"})
 	})
+	// Public keys for verifying our access tokens
+	g.GET("/.well-known/jwks.json", app.jwks)
+
 	// Health + auth (public)
 	v1 := g.Group("/api/v1")
 	{
@@ -34,34 +39,115 @@ func (app *application) routes() http.Handler {
 		})
 		v1.POST("/auth/register", app.registerUser)
 		v1.POST("/auth/login", app.login)
+		v1.POST("/auth/login/2fa", app.loginTwoFactor)
+		v1.POST("/auth/refresh", app.refreshToken)
+		v1.POST("/auth/logout", app.logout)
+		v1.POST("/auth/verify-email", app.verifyEmail)
+		v1.POST("/auth/password/forgot", app.forgotPassword)
+		v1.POST("/auth/password/reset", app.resetPassword)
+		v1.GET("/auth/oidc/:provider/start", app.startOIDCLogin)
+		v1.GET("/auth/oidc/:provider/callback", app.oidcCallback)
+
+		// Public and unlisted events can be read without an account
+		v1.GET("/events/discover", app.discoverEvents)
+		v1.GET("/events/search", app.OptionalAuthMiddleware(), app.searchEvents)
+		v1.GET("/events/:id", app.OptionalAuthMiddleware(), app.getEventByID)
+		v1.GET("/events/:id/occurrences", app.OptionalAuthMiddleware(), app.listOccurrences)
+		v1.GET("/events/:id/ics", app.OptionalAuthMiddleware(), app.getEventICS)
+		v1.GET("/events/:id/registration-form", app.OptionalAuthMiddleware(), app.getRegistrationForm)
+
+		// Calendar applications cannot log in; the feed URL carries a secret
+		v1.GET("/calendar/:token", app.calendarFeed)
+
+		// Invitation landing page; declining needs no account either
+		v1.GET("/invitations/:token", app.getInvitation)
+		v1.POST("/invitations/:token/decline", app.OptionalAuthMiddleware(), app.requireScope(database.APIKeyScopeReadWrite), app.declineInvitation)
 	}
 
-	// Protected group (requires JWT). Use an empty path segment so
+	// Protected group (requires JWT or API key). Use an empty path segment so
 	// downstream handlers generate clean /api/v1/... routes instead of //.
+	// Writes need a read-write key; account management needs a real login.
 	auth := v1.Group("")
 	auth.Use(app.AuthMiddleware())
 	{
 		// User management
 		auth.GET("/auth/me", app.getCurrentUser)
-		auth.PUT("/auth/me", app.updateCurrentUser)
-		auth.DELETE("/auth/me", app.deleteCurrentUser)
+		auth.PUT("/auth/me", app.requireSession(), app.updateCurrentUser)
+		auth.DELETE("/auth/me", app.requireSession(), app.deleteCurrentUser)
 		auth.GET("/users/:id", app.getUserByID)
-		auth.POST("/auth/me/avatar", app.uploadProfilePicture)
+		auth.POST("/auth/me/avatar", app.requireScope(database.APIKeyScopeReadWrite), app.uploadProfilePicture)
+		auth.GET("/auth/me/sessions", app.requireSession(), app.listSessions)
+		auth.DELETE("/auth/me/sessions/:id", app.requireSession(), app.revokeSession)
+		auth.POST("/auth/verify-email/resend", app.requireSession(), app.resendVerificationEmail)
+		auth.POST("/auth/me/2fa/setup", app.requireSession(), app.setupTwoFactor)
+		auth.POST("/auth/me/2fa/enable", app.requireSession(), app.enableTwoFactor)
+		auth.POST("/auth/me/2fa/disable", app.requireSession(), app.disableTwoFactor)
+		auth.POST("/auth/me/api-keys", app.requireSession(), app.createAPIKey)
+		auth.GET("/auth/me/api-keys", app.requireSession(), app.listAPIKeys)
+		auth.DELETE("/auth/me/api-keys/:id", app.requireSession(), app.deleteAPIKey)
+		auth.POST("/auth/me/calendar", app.requireSession(), app.createCalendarFeed)
+		auth.GET("/auth/me/calendar", app.requireSession(), app.getCalendarFeed)
+		auth.DELETE("/auth/me/calendar", app.requireSession(), app.deleteCalendarFeed)
 
 		// Event queries
 		auth.GET("/events", app.getAllEvents)
-		auth.GET("/events/:id", app.getEventByID)
 
 		// Attendee management
 		auth.GET("/events/:id/attendees", app.getAttendeesForEvent)
+		auth.GET("/events/:id/attendees/export", app.exportAttendees)
+		auth.POST("/events/:id/attendees/import", app.requireScope(database.APIKeyScopeReadWrite), app.importAttendees)
 		auth.GET("/events/:id/attendees/:userId", app.getEventsByAttendee)
-		auth.POST("/events/:id/attendees/:userId", app.addAttendeeToEvent)
-		auth.DELETE("/events/:id/attendees/:userId", app.deleteAttendeeFromEvent)
+		auth.POST("/events/:id/attendees/:userId", app.requireScope(database.APIKeyScopeReadWrite), app.addAttendeeToEvent)
+		auth.DELETE("/events/:id/attendees/:userId", app.requireScope(database.APIKeyScopeReadWrite), app.deleteAttendeeFromEvent)
+
+		// Self-service RSVP
+		auth.GET("/events/:id/rsvp", app.getRSVP)
+		auth.POST("/events/:id/rsvp", app.requireScope(database.APIKeyScopeReadWrite), app.rsvpEvent)
+		auth.DELETE("/events/:id/rsvp", app.requireScope(database.APIKeyScopeReadWrite), app.cancelRSVP)
+
+		// Registration forms and answers
+		auth.PUT("/events/:id/registration-form", app.requireScope(database.APIKeyScopeReadWrite), app.setRegistrationForm)
+		auth.GET("/events/:id/registration-answers", app.listRegistrationAnswers)
+		auth.GET("/events/:id/registration-answers/summary", app.summarizeRegistrationAnswers)
+
+		// Tickets and check-in at the door
+		auth.GET("/events/:id/ticket", app.getTicket)
+		auth.POST("/events/:id/check-in", app.requireScope(database.APIKeyScopeReadWrite), app.checkIn)
+		auth.GET("/events/:id/check-in/stats", app.checkInStats)
+
+		// Invitations
+		auth.GET("/events/:id/invitations", app.listInvitations)
+		auth.POST("/events/:id/invitations", app.requireScope(database.APIKeyScopeReadWrite), app.createInvitation)
+		auth.DELETE("/events/:id/invitations/:invitationId", app.requireScope(database.APIKeyScopeReadWrite), app.revokeInvitation)
+		auth.POST("/invitations/:token/accept", app.requireScope(database.APIKeyScopeReadWrite), app.acceptInvitation)
+
+		// Organizer team
+		auth.GET("/events/:id/organizers", app.listOrganizers)
+		auth.POST("/events/:id/organizers", app.requireScope(database.APIKeyScopeReadWrite), app.addOrganizer)
+		auth.DELETE("/events/:id/organizers/:userId", app.requireScope(database.APIKeyScopeReadWrite), app.removeOrganizer)
+		auth.POST("/events/:id/transfer", app.requireSession(), app.transferEvent)
 
 		// Event mutations
-		auth.POST("/events", app.createEvent)
-		auth.PUT("/events/:id", app.updateEvent)
-		auth.DELETE("/events/:id", app.deleteEvent)
+		auth.POST("/events", app.requireScope(database.APIKeyScopeReadWrite), app.requireVerifiedEmail(), app.createEvent)
+		auth.POST("/events/batch", app.requireScope(database.APIKeyScopeReadWrite), app.batchEvents)
+		auth.PUT("/events/:id", app.requireScope(database.APIKeyScopeReadWrite), app.updateEvent)
+		auth.PATCH("/events/:id", app.requireScope(database.APIKeyScopeReadWrite), app.patchEvent)
+		auth.DELETE("/events/:id", app.requireScope(database.APIKeyScopeReadWrite), app.deleteEvent)
+		auth.PUT("/events/:id/occurrences/:occurrence", app.requireScope(database.APIKeyScopeReadWrite), app.updateOccurrence)
+		auth.DELETE("/events/:id/occurrences/:occurrence", app.requireScope(database.APIKeyScopeReadWrite), app.cancelOccurrence)
+	}
+
+	// Moderation and administration. Everything here needs an interactive
+	// login; changing roles is reserved for admins.
+	admin := auth.Group("/admin", app.requireSession(), app.requireRole(database.RoleModerator))
+	{
+		admin.GET("/users", app.adminListUsers)
+		admin.PUT("/users/:id/role", app.requireRole(database.RoleAdmin), app.adminSetRole)
+		admin.POST("/users/:id/disable", app.adminDisableUser)
+		admin.POST("/users/:id/enable", app.adminEnableUser)
+		admin.DELETE("/events/:id", app.adminDeleteEvent)
+		admin.GET("/events/invalid-schedules", app.adminListInvalidSchedules)
+		admin.PUT("/events/:id/schedule", app.requireRole(database.RoleAdmin), app.adminSetSchedule)
 	}
 
 	// Swagger documentation
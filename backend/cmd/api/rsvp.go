@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rsvpRequest is the body of POST /events/:id/rsvp. An empty body means
+// going. Answers to the event's registration form are keyed by field id;
+// saying yes needs every required one, unless it was answered before.
+type rsvpRequest struct {
+	Status  string                     `json:"status" binding:"omitempty,oneof=going declined"`
+	Answers map[string]json.RawMessage `json:"answers"`
+}
+
+// getRSVP handles GET /events/:id/rsvp. Like the other RSVP endpoints it
+// takes an occurrence query parameter to answer for one occurrence of a
+// recurring event rather than the whole series.
+//
+// @Summary Get my RSVP
+// @Description Show the current user's answer to an event
+// @Tags RSVP
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Success 200 {object} database.Attendee "RSVP"
+// @Failure 404 {object} gin.H "Event or RSVP not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/rsvp [get]
+func (app *application) getRSVP(c *gin.Context) {
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
+		return
+	}
+
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	attendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, occurrence, app.getUserFromContext(c).Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve RSVP"})
+		return
+	}
+	if attendee == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have not responded to this event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attendee)
+}
+
+// rsvpEvent handles POST /events/:id/rsvp. Anyone who can see the event can
+// answer it. Saying yes to a full event puts the user on the waitlist; the
+// response status tells which one happened.
+//
+// @Summary RSVP to an event
+// @Description Say you are going (or not) to an event; joins the waitlist when it is full
+// @Tags RSVP
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Param body body rsvpRequest false "going (default) or declined, and registration answers"
+// @Success 200 {object} database.Attendee "RSVP with status going, waitlisted or declined"
+// @Failure 400 {object} gin.H "Invalid request body or registration answers"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/rsvp [post]
+func (app *application) rsvpEvent(c *gin.Context) {
+	var input rsvpRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.Status == "" {
+		input.Status = database.AttendeeGoing
+	}
+
+	app.respondToEvent(c, input.Status, input.Answers)
+}
+
+// cancelRSVP handles DELETE /events/:id/rsvp. If the user had a spot, the
+// next person on the waitlist gets it.
+//
+// @Summary Cancel my RSVP
+// @Description Withdraw from an event or its waitlist
+// @Tags RSVP
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Success 200 {object} database.Attendee "Cancelled RSVP"
+// @Failure 404 {object} gin.H "Event or RSVP not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/rsvp [delete]
+func (app *application) cancelRSVP(c *gin.Context) {
+	app.respondToEvent(c, database.AttendeeCancelled, nil)
+}
+
+func (app *application) respondToEvent(c *gin.Context, status string, submitted map[string]json.RawMessage) {
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
+		return
+	}
+
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	answers, ok := app.checkAnswers(c, event, occurrence, user.Id, submitted, status == database.AttendeeGoing)
+	if !ok {
+		return
+	}
+
+	attendee, promoted, err := app.models.Attendees.Respond(c.Request.Context(), event.Id, occurrence, user.Id, status, answers)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "You have not responded to this event"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save RSVP"})
+		return
+	}
+
+	app.notifyPromoted(event, promoted)
+	c.JSON(http.StatusOK, attendee)
+}
+
+// notifyPromoted tells people who moved up from the waitlist that they have
+// a spot now.
+func (app *application) notifyPromoted(event *database.Event, promoted []*database.Attendee) {
+	for _, attendee := range promoted {
+		user, err := app.models.Users.GetUserByID(attendee.UserId)
+		if err != nil || user == nil {
+			log.Printf("failed to notify user %d about a spot in event %d: %v", attendee.UserId, event.Id, err)
+			continue
+		}
+		app.sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: fmt.Sprintf("You're in: %s", event.Name),
+			Body: fmt.Sprintf("Hi %s,\n\na spot opened up and you moved off the waitlist for \"%s\". See you there!\n\n%s/events/%d\n",
+				user.Name, event.Name, app.frontendURL, event.Id),
+		})
+	}
+}
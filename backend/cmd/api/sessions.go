@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionResponse is a session as shown to its owner. Current marks the
+// session the request itself was made with.
+type sessionResponse struct {
+	*database.Session
+	Current bool `json:"current"`
+}
+
+// listSessions handles GET /auth/me/sessions.
+//
+// @Summary List my sessions
+// @Description List the active logins of the authenticated user
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} sessionResponse "Active sessions"
+// @Failure 401 {object} gin.H "Unauthorized"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/sessions [get]
+func (app *application) listSessions(c *gin.Context) {
+	user := app.getUserFromContext(c)
+	current := app.getSessionFromContext(c)
+
+	sessions, err := app.models.Sessions.GetActiveByUser(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions"})
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			Session: session,
+			Current: current != nil && current.Id == session.Id,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// revokeSession handles DELETE /auth/me/sessions/:id.
+// Revoking the current session is allowed and behaves like a logout.
+//
+// @Summary Revoke a session
+// @Description Revoke one of the authenticated user's sessions
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 204 "Session revoked"
+// @Failure 400 {object} gin.H "Invalid session ID"
+// @Failure 404 {object} gin.H "Session not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/sessions/{id} [delete]
+func (app *application) revokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	if err := app.models.Sessions.Revoke(c.Request.Context(), user.Id, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/tickets"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ticketQRSize is the width and height of ticket PNGs in pixels.
+const ticketQRSize = 320
+
+// ticketResponse is an attendee's ticket. QRPNG is a data URL that can go
+// straight into an img tag.
+type ticketResponse struct {
+	Code       string             `json:"code"`
+	EventId    int                `json:"eventId"`
+	Occurrence string             `json:"occurrence,omitempty"`
+	Attendee   *database.Attendee `json:"attendee"`
+	QRPNG      string             `json:"qr_png"`
+}
+
+type checkInRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// getTicket handles GET /events/:id/ticket. People who are going get a
+// ticket; its code is signed, so it cannot be made up for someone else.
+// format=png or format=svg returns just the QR code image. Tickets for
+// recurring events are for one occurrence, the next one unless occurrence
+// is given; going to the whole series gets a ticket for each occurrence not
+// answered on its own.
+//
+// @Summary Get my ticket
+// @Description Show the current user's ticket for an event, with a QR code to scan at the door
+// @Tags Tickets
+// @Produce json
+// @Produce image/png
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Param format query string false "json (default), png or svg"
+// @Success 200 {object} ticketResponse "Ticket"
+// @Failure 400 {object} gin.H "Invalid format"
+// @Failure 404 {object} gin.H "Event not found or no ticket"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/ticket [get]
+func (app *application) getTicket(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "png" && format != "svg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, png, svg"})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionViewEvent)
+	if event == nil {
+		return
+	}
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	userId := app.getUserFromContext(c).Id
+	attendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, occurrence, userId)
+	if err == nil && attendee == nil && occurrence != "" {
+		attendee, err = app.models.Attendees.GetByEventAndAttendee(event.Id, "", userId)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ticket"})
+		return
+	}
+	if attendee == nil || attendee.Status != database.AttendeeGoing {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have no ticket for this event; only people who are going get one"})
+		return
+	}
+
+	if occurrence == "" && event.RRule != nil {
+		// Still admit to an occurrence that has started
+		now := time.Now()
+		next, err := app.models.Occurrences.List(event, now.Add(-event.EndsAt.Sub(event.StartsAt)), now.AddDate(1, 0, 0), 1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ticket"})
+			return
+		}
+		if len(next) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This series has no upcoming occurrence"})
+			return
+		}
+		occurrence = next[0].Occurrence
+	}
+
+	code := app.tickets.Code(tickets.Ticket{EventId: event.Id, AttendeeId: attendee.Id, Occurrence: occurrence})
+	switch format {
+	case "svg":
+		svg, err := tickets.QRSVG(code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render ticket"})
+			return
+		}
+		c.Data(http.StatusOK, "image/svg+xml", svg)
+		return
+	}
+
+	png, err := tickets.QRPNG(code, ticketQRSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render ticket"})
+		return
+	}
+	if format == "png" {
+		c.Data(http.StatusOK, "image/png", png)
+		return
+	}
+
+	c.JSON(http.StatusOK, ticketResponse{
+		Code:       code,
+		EventId:    event.Id,
+		Occurrence: occurrence,
+		Attendee:   attendee,
+		QRPNG:      "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// checkIn handles POST /events/:id/check-in. Door staff post the code they
+// scanned; each ticket is accepted once, at the occurrence it names. A
+// replayed ticket gets 409 with the time it was first used.
+//
+// @Summary Check an attendee in
+// @Description Scan a ticket at the door. Owners, editors and check-in staff may do this.
+// @Tags Tickets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param body body checkInRequest true "Scanned ticket code"
+// @Success 200 {object} gin.H "Attendee checked in, with their name"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 403 {object} gin.H "Not allowed to check people in"
+// @Failure 404 {object} gin.H "Event not found, or not a ticket for this event"
+// @Failure 409 {object} gin.H "Ticket already used, or its holder is no longer going"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/check-in [post]
+func (app *application) checkIn(c *gin.Context) {
+	var input checkInRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := app.authorizeEvent(c, actionCheckIn)
+	if event == nil {
+		return
+	}
+
+	ticket, err := app.tickets.Parse(input.Code)
+	if err != nil || ticket.EventId != event.Id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This is not a ticket for this event"})
+		return
+	}
+
+	if ticket.Occurrence != "" {
+		start, _ := time.Parse(time.RFC3339, ticket.Occurrence)
+		occurrence, err := app.models.Occurrences.Get(event, start)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in"})
+			return
+		}
+		if occurrence == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This ticket is no longer valid"})
+			return
+		}
+	}
+
+	attendee, err := app.models.Attendees.CheckIn(c.Request.Context(), event.Id, ticket.AttendeeId, ticket.Occurrence, app.getUserFromContext(c).Id)
+	switch {
+	case errors.Is(err, database.ErrTicketNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "This ticket is no longer valid"})
+		return
+	case errors.Is(err, database.ErrAlreadyCheckedIn):
+		c.JSON(http.StatusConflict, gin.H{"error": "This ticket was already used", "attendee": attendee})
+		return
+	case errors.Is(err, database.ErrTicketNotGoing):
+		c.JSON(http.StatusConflict, gin.H{"error": "The ticket holder is no longer going", "attendee": attendee})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in"})
+		return
+	}
+
+	// Staff want to greet people by name
+	response := gin.H{"message": "Checked in", "attendee": attendee}
+	if ticket.Occurrence != "" {
+		response["occurrence"] = ticket.Occurrence
+	}
+	if user, err := app.models.Users.GetUserByID(attendee.UserId); err == nil && user != nil {
+		response["name"] = user.Name
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// checkInStats handles GET /events/:id/check-in/stats, for watching
+// arrivals during the event.
+//
+// @Summary Check-in numbers
+// @Description How many of the people going have arrived
+// @Tags Tickets
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param occurrence query string false "RFC3339 start of one occurrence of a recurring event"
+// @Success 200 {object} database.CheckInStats "Registered and checked-in counts"
+// @Failure 403 {object} gin.H "Not allowed to view attendees"
+// @Failure 404 {object} gin.H "Event not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/events/{id}/check-in/stats [get]
+func (app *application) checkInStats(c *gin.Context) {
+	event := app.authorizeEvent(c, actionViewAttendees)
+	if event == nil {
+		return
+	}
+	occurrence, ok := app.occurrenceParam(c, event)
+	if !ok {
+		return
+	}
+
+	stats, err := app.models.Attendees.CheckInStats(event.Id, occurrence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve check-in stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
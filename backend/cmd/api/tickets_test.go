@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/tickets"
+	"strings"
+	"testing"
+)
+
+func TestCheckIn(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+	ctx := context.Background()
+	event := newTestEvent(t, app, 0, "")
+	owner, err := app.models.Users.GetUserByID(event.OwnerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsvps := map[string]*database.Attendee{}
+	users := map[string]*database.User{}
+	for _, name := range []string{"ann", "bob"} {
+		users[name] = newTestUser(t, app, name)
+		if rsvps[name], _, err = app.models.Attendees.Respond(ctx, event.Id, "", users[name].Id, database.AttendeeGoing, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := app.models.Attendees.Respond(ctx, event.Id, "", users["bob"].Id, database.AttendeeCancelled, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	code := func(attendeeId int) string {
+		return app.tickets.Code(tickets.Ticket{EventId: event.Id, AttendeeId: attendeeId})
+	}
+	ann := code(rsvps["ann"].Id)
+	forged := strings.Replace(ann, fmt.Sprintf(".%d.", rsvps["ann"].Id), fmt.Sprintf(".%d.", rsvps["bob"].Id), 1)
+
+	tests := []struct {
+		name   string
+		staff  *database.User
+		code   string
+		status int
+	}{
+		{"attendee scanning their own ticket", users["ann"], ann, http.StatusForbidden},
+		{"forged attendee id", owner, forged, http.StatusNotFound},
+		{"another event's ticket", owner, app.tickets.Code(tickets.Ticket{EventId: event.Id + 1, AttendeeId: rsvps["ann"].Id}), http.StatusNotFound},
+		{"signed with another key", owner, tickets.NewSigner("other").Code(tickets.Ticket{EventId: event.Id, AttendeeId: rsvps["ann"].Id}), http.StatusNotFound},
+		{"unknown attendee", owner, code(999), http.StatusNotFound},
+		{"cancelled RSVP", owner, code(rsvps["bob"].Id), http.StatusConflict},
+		{"valid ticket", owner, ann, http.StatusOK},
+		{"replayed ticket", owner, ann, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(checkInRequest{Code: tt.code})
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/events/%d/check-in", event.Id), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", bearer(t, app, tt.staff))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	stats, err := app.models.Attendees.CheckInStats(event.Id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Registered != 1 || stats.CheckedIn != 1 {
+		t.Errorf("stats = %+v, want ann registered and checked in", stats)
+	}
+}
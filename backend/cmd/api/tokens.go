@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"rest-api-in-gin/internal/database"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// newAccessToken signs a short-lived JWT for the user. AuthMiddleware only
+// ever validates this token; refresh tokens are never accepted there. The
+// "sid" claim ties the token to a session so it can be revoked early.
+func (app *application) newAccessToken(userId, sessionId int) (string, error) {
+	return app.keys.Sign(jwt.MapClaims{
+		"userId": userId,
+		"sid":    sessionId,
+		"exp":    time.Now().Add(app.accessTokenTTL).Unix(),
+	})
+}
+
+// issueTokenPair records a new session for the requesting device, starts a
+// refresh token family for it and signs the matching access token.
+func (app *application) issueTokenPair(c *gin.Context, userId int) (*loginResponse, error) {
+	session := database.Session{
+		UserId:    userId,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+	if err := app.models.Sessions.Insert(&session); err != nil {
+		return nil, err
+	}
+
+	familyId, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := database.RefreshToken{
+		UserId:    userId,
+		SessionId: session.Id,
+		FamilyId:  familyId,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().Add(app.refreshTokenTTL),
+	}
+	if err := app.models.RefreshTokens.Insert(&record); err != nil {
+		return nil, err
+	}
+
+	access, err := app.newAccessToken(userId, session.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loginResponse{Token: access, RefreshToken: refresh, ExpiresIn: int64(app.accessTokenTTL.Seconds())}, nil
+}
+
+// rotateTokenPair swaps a presented refresh token for a fresh pair. Errors
+// from the database package (reuse, expiry, unknown token) are passed through
+// so the handler can map them to status codes.
+func (app *application) rotateTokenPair(ctx context.Context, presented string) (*loginResponse, error) {
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	next := database.RefreshToken{
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().Add(app.refreshTokenTTL),
+	}
+	if _, err := app.models.RefreshTokens.Rotate(ctx, hashToken(presented), &next); err != nil {
+		return nil, err
+	}
+
+	access, err := app.newAccessToken(next.UserId, next.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loginResponse{Token: access, RefreshToken: refresh, ExpiresIn: int64(app.accessTokenTTL.Seconds())}, nil
+}
+
+// signPurposeToken signs a single-purpose JWT such as an email verification
+// link. The "purpose" claim keeps these tokens from being usable anywhere
+// else; AuthMiddleware rejects any token that carries one.
+func (app *application) signPurposeToken(purpose string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	return app.keys.Sign(claims)
+}
+
+// parsePurposeToken verifies a token created by signPurposeToken and checks
+// that it was issued for purpose.
+func (app *application) parsePurposeToken(purpose, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, app.keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+// randomToken returns n random bytes encoded as URL-safe base64.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used for every opaque secret we store. The tokens are high
+// entropy, so a fast unsalted hash is sufficient here (unlike passwords).
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"net/http"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/totp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	twoFactorChallengePurpose = "2fa_challenge"
+	twoFactorChallengeTTL     = 5 * time.Minute
+	recoveryCodeCount         = 10
+)
+
+// twoFactorCodeRequest carries a code from the authenticator app or, where
+// accepted, one of the recovery codes.
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// twoFactorLoginRequest completes a login that was answered with a challenge.
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// twoFactorChallengeResponse replaces loginResponse for accounts with 2FA.
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// twoFactorPolicy limits code guessing for a single account during the second
+// login step. Six digits are easy to brute force without it.
+var twoFactorPolicy = emailLoginPolicy
+
+// newTwoFactorChallenge signs the short-lived token that proves the password
+// step succeeded. It can only be redeemed at /auth/login/2fa.
+func (app *application) newTwoFactorChallenge(userId int) (*twoFactorChallengeResponse, error) {
+	token, err := app.signPurposeToken(twoFactorChallengePurpose, twoFactorChallengeTTL, jwt.MapClaims{"userId": userId})
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// openTOTPSecret decrypts the user's TOTP secret. Secrets stored in the
+// clear before they were encrypted get encrypted on the way.
+func (app *application) openTOTPSecret(ctx context.Context, user *database.User) (string, error) {
+	secret, err := app.totpSecrets.Open(user.TOTPSecret)
+	if err != nil || secret == "" || totp.Sealed(user.TOTPSecret) {
+		return secret, err
+	}
+	sealed, err := app.totpSecrets.Seal(secret)
+	if err != nil {
+		return "", err
+	}
+	if err := app.models.Users.ReplaceTOTPSecret(ctx, user.Id, user.TOTPSecret, sealed); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code that has not been
+// used before or an unused recovery code. Recovery codes keep working when
+// the secret cannot be decrypted, for example after TOTP_ENCRYPTION_KEY
+// changed.
+func (app *application) verifySecondFactor(ctx context.Context, user *database.User, code string) (bool, error) {
+	secret, err := app.openTOTPSecret(ctx, user)
+	switch {
+	case errors.Is(err, totp.ErrUnsealable):
+		log.Printf("cannot decrypt the TOTP secret of user %d: %v", user.Id, err)
+	case err != nil:
+		return false, err
+	default:
+		if counter, ok := totp.Validate(secret, code, time.Now(), 1); ok {
+			return app.models.Users.ConsumeTOTPCounter(ctx, user.Id, counter)
+		}
+	}
+	return app.models.RecoveryCodes.Use(ctx, user.Id, hashToken(normalizeRecoveryCode(code)))
+}
+
+// newRecoveryCodes returns fresh codes formatted for display together with
+// the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// setupTwoFactor handles POST /auth/me/2fa/setup.
+// It generates a new secret and returns it as an otpauth:// URI for the
+// authenticator app. 2FA stays off until the user confirms a code.
+//
+// @Summary Start 2FA setup
+// @Description Generate a TOTP secret and otpauth URI for the authenticated user
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} gin.H "Secret and otpauth URI"
+// @Failure 409 {object} gin.H "2FA already enabled"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/2fa/setup [post]
+func (app *application) setupTwoFactor(c *gin.Context) {
+	user := app.getUserFromContext(c)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	sealed, err := app.totpSecrets.Seal(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store secret"})
+		return
+	}
+	stored, err := app.models.Users.SetPendingTOTPSecret(c.Request.Context(), user.Id, sealed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store secret"})
+		return
+	}
+	if !stored {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(app.totpIssuer, user.Email, secret),
+	})
+}
+
+// enableTwoFactor handles POST /auth/me/2fa/enable.
+// The code proves the authenticator app holds the secret. The response
+// contains the recovery codes; they are shown only this once.
+//
+// @Summary Enable 2FA
+// @Description Confirm a TOTP code to enable 2FA and receive recovery codes
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body twoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} gin.H "Recovery codes"
+// @Failure 400 {object} gin.H "Invalid code or setup not started"
+// @Failure 409 {object} gin.H "2FA already enabled"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/2fa/enable [post]
+func (app *application) enableTwoFactor(c *gin.Context) {
+	var input twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	if user.TwoFactorEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start setup first"})
+		return
+	}
+
+	secret, err := app.openTOTPSecret(c.Request.Context(), user)
+	if errors.Is(err, totp.ErrUnsealable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start setup again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	counter, ok := totp.Validate(secret, input.Code, time.Now(), 1)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	if err := app.models.Users.EnableTOTP(c.Request.Context(), user.Id, counter, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	app.recordAudit(c, "2fa.enabled", &user.Id, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// disableTwoFactor handles POST /auth/me/2fa/disable.
+//
+// @Summary Disable 2FA
+// @Description Turn 2FA off with a current TOTP code or a recovery code
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body twoFactorCodeRequest true "TOTP or recovery code"
+// @Success 204 "2FA disabled"
+// @Failure 400 {object} gin.H "Invalid code or 2FA not enabled"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/me/2fa/disable [post]
+func (app *application) disableTwoFactor(c *gin.Context) {
+	var input twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := app.getUserFromContext(c)
+	if user.TwoFactorEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	ok, err := app.verifySecondFactor(c.Request.Context(), user, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := app.models.Users.DisableTOTP(c.Request.Context(), user.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	app.recordAudit(c, "2fa.disabled", &user.Id, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// loginTwoFactor handles POST /auth/login/2fa requests.
+// It exchanges the challenge token from /auth/login plus a TOTP or recovery
+// code for the normal access and refresh tokens.
+//
+// @Summary Complete 2FA login
+// @Description Exchange a login challenge and a TOTP or recovery code for tokens
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body twoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} loginResponse "Login successful"
+// @Failure 400 {object} gin.H "Invalid request body"
+// @Failure 401 {object} gin.H "Invalid challenge or code"
+// @Failure 429 {object} gin.H "Too many failed attempts; see Retry-After"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /api/v1/auth/login/2fa [post]
+func (app *application) loginTwoFactor(c *gin.Context) {
+	var input twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := app.parsePurposeToken(twoFactorChallengePurpose, input.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	userId, _ := claims["userId"].(float64)
+
+	user, err := app.models.Users.GetUserByID(int(userId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+	if user == nil || user.TwoFactorEnabledAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	if user.Disabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	key := "2fa:" + strconv.Itoa(user.Id)
+	if !app.checkLoginLockout(c, key) {
+		return
+	}
+
+	ok, err := app.verifySecondFactor(c.Request.Context(), user, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		lockout, err := app.loginLimiter.Failure(c.Request.Context(), key, twoFactorPolicy)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+			return
+		}
+		if lockout > 0 {
+			app.recordAudit(c, "login.lockout", &user.Id, map[string]any{"key": key, "lockout_seconds": int(lockout.Seconds())})
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := app.loginLimiter.Success(c.Request.Context(), key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+		return
+	}
+
+	tokens, err := app.issueTokenPair(c, user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"rest-api-in-gin/internal/database"
+	"rest-api-in-gin/internal/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' || code != strings.ToLower(code) {
+			t.Errorf("code %q is not xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q repeats", code)
+		}
+		seen[code] = true
+
+		// Users may type codes in any case, with or without the dash
+		for _, typed := range []string{code, strings.ToUpper(code), strings.ReplaceAll(code, "-", ""), " " + code[:5] + " " + code[6:] + " "} {
+			if hashToken(normalizeRecoveryCode(typed)) != hashes[i] {
+				t.Errorf("%q does not match the hash of %q", typed, code)
+			}
+		}
+	}
+}
+
+// enableTestTwoFactor turns on 2FA for a new user with secret stored as
+// given and returns the user and their recovery codes.
+func enableTestTwoFactor(t *testing.T, app *application, email, stored string) (*database.User, []string) {
+	t.Helper()
+	ctx := context.Background()
+	user := database.User{Email: email, Name: "Ann", Password: "x"}
+	if err := app.models.Users.Insert(&user); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := app.models.Users.SetPendingTOTPSecret(ctx, user.Id, stored); err != nil || !ok {
+		t.Fatalf("SetPendingTOTPSecret = %v, %v", ok, err)
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.EnableTOTP(ctx, user.Id, totp.Counter(time.Now())-2, hashes); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := app.models.Users.GetUserByID(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded, codes
+}
+
+func TestVerifySecondFactor(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := app.totpSecrets.Seal(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, recovery := enableTestTwoFactor(t, app, "ann@example.com", sealed)
+
+	code, err := totp.CodeAt(secret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"current code", code, true},
+		{"same code again", code, false},
+		{"recovery code", strings.ToUpper(recovery[0]), true},
+		{"same recovery code again", recovery[0], false},
+		{"another recovery code", recovery[1], true},
+		{"wrong code", "abcde-fghij", false},
+	}
+	for _, step := range steps {
+		ok, err := app.verifySecondFactor(ctx, user, step.code)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if ok != step.ok {
+			t.Errorf("%s: ok = %v, want %v", step.name, ok, step.ok)
+		}
+	}
+	if left, err := app.models.RecoveryCodes.CountUnused(user.Id); err != nil || left != recoveryCodeCount-2 {
+		t.Errorf("unused recovery codes = %d, %v; want %d", left, err, recoveryCodeCount-2)
+	}
+}
+
+func TestVerifySecondFactorSealsPlainSecret(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ := enableTestTwoFactor(t, app, "ann@example.com", secret)
+
+	code, err := totp.CodeAt(secret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := app.verifySecondFactor(ctx, user, code); err != nil || !ok {
+		t.Fatalf("verifySecondFactor = %v, %v", ok, err)
+	}
+
+	stored, err := app.models.Users.GetUserByID(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !totp.Sealed(stored.TOTPSecret) {
+		t.Fatalf("secret still stored in the clear: %q", stored.TOTPSecret)
+	}
+	if opened, err := app.totpSecrets.Open(stored.TOTPSecret); err != nil || opened != secret {
+		t.Errorf("stored secret opens to %q, %v; want %q", opened, err, secret)
+	}
+}
+
+func TestVerifySecondFactorWithAnotherKey(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := totp.NewSecretBox("previous-key").Seal(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, recovery := enableTestTwoFactor(t, app, "ann@example.com", sealed)
+
+	code, err := totp.CodeAt(secret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := app.verifySecondFactor(ctx, user, code); err != nil || ok {
+		t.Errorf("TOTP code with an undecryptable secret = %v, %v; want refused", ok, err)
+	}
+	if ok, err := app.verifySecondFactor(ctx, user, recovery[0]); err != nil || !ok {
+		t.Errorf("recovery code = %v, %v; want accepted", ok, err)
+	}
+}
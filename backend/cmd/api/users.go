@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -102,6 +103,11 @@ func (app *application) updateCurrentUser(c *gin.Context) {
 			return
 		}
 		params.PasswordHash = hashed
+
+		// A password change signs the user out everywhere else
+		if session := app.getSessionFromContext(c); session != nil {
+			params.KeepSessionId = session.Id
+		}
 	}
 
 	if input.ProfilePicture != nil {
@@ -114,6 +120,13 @@ func (app *application) updateCurrentUser(c *gin.Context) {
 		return
 	}
 
+	// A new address has to be confirmed before it counts as verified
+	if updatedUser != nil && updatedUser.Email != user.Email {
+		if err := app.sendVerificationEmail(updatedUser); err != nil {
+			log.Printf("failed to create verification email for user %d: %v", updatedUser.Id, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, updatedUser)
 }
 
@@ -163,12 +176,7 @@ func (app *application) uploadProfilePicture(c *gin.Context) {
 		return
 	}
 
-	scheme := "http"
-	if c.Request.TLS != nil {
-		scheme = "https"
-	}
-	baseURL := fmt.Sprintf("%s://%s", scheme, c.Request.Host)
-	url := fmt.Sprintf("%s/uploads/%s", baseURL, filename)
+	url := fmt.Sprintf("%s/uploads/%s", requestBaseURL(c), filename)
 
 	// We only return the canonical URL here. The client will call PUT /auth/me
 	// with this URL once the user confirms the change, so we avoid touching the
@@ -195,3 +203,13 @@ func (app *application) deleteCurrentUser(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// requestBaseURL is the scheme and host the request was made to, for links
+// back to this API.
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
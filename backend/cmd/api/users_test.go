@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-in-gin/internal/database"
+	"testing"
+)
+
+func TestPasswordChangeRevokesOtherSessions(t *testing.T) {
+	app := newTestApp(t)
+	handler := app.routes()
+
+	user := database.User{Email: "ann@example.com", Name: "Ann", Password: "x"}
+	if err := app.models.Users.Insert(&user); err != nil {
+		t.Fatal(err)
+	}
+	current := database.Session{UserId: user.Id}
+	other := database.Session{UserId: user.Id}
+	for _, session := range []*database.Session{&current, &other} {
+		if err := app.models.Sessions.Insert(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := app.models.APIKeys.Insert(&database.APIKey{UserId: user.Id, Name: "ci", Prefix: "evk_abc", KeyHash: hashToken("evk_abc"), Scope: database.APIKeyScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.newAccessToken(user.Id, current.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Other profile changes leave the credentials alone
+	if rec := put(`{"name": "Ann B"}`); rec.Code != http.StatusOK {
+		t.Fatalf("rename: status %d: %s", rec.Code, rec.Body)
+	}
+	if keys, err := app.models.APIKeys.GetAllByUser(user.Id); err != nil || len(keys) != 1 {
+		t.Fatalf("API keys after rename = %d, %v; want 1", len(keys), err)
+	}
+
+	if rec := put(`{"password": "new-password"}`); rec.Code != http.StatusOK {
+		t.Fatalf("password change: status %d: %s", rec.Code, rec.Body)
+	}
+	sessions, err := app.models.Sessions.GetActiveByUser(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Id != current.Id {
+		t.Errorf("active sessions = %+v, want only the current one", sessions)
+	}
+	if keys, err := app.models.APIKeys.GetAllByUser(user.Id); err != nil || len(keys) != 1 {
+		t.Errorf("API keys after password change = %d, %v; want the key kept", len(keys), err)
+	}
+}
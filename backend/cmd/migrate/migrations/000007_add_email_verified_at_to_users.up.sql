@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+
+-- Accounts that existed before verification was introduced are trusted as-is.
+UPDATE users SET email_verified_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+-- Disabled accounts keep their data but can no longer sign in.
+ALTER TABLE users ADD COLUMN disabled_at DATETIME;
@@ -0,0 +1,17 @@
+-- People who run an event. events.owner_id stays the owner of record and
+-- always has a matching 'owner' row here.
+CREATE TABLE IF NOT EXISTS event_organizers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_organizers_event_user ON event_organizers(event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_event_organizers_user_id ON event_organizers(user_id);
+
+INSERT INTO event_organizers (event_id, user_id, role)
+SELECT id, owner_id, 'owner' FROM events;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_visibility;
+
+ALTER TABLE events DROP COLUMN visibility;
@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN visibility TEXT NOT NULL DEFAULT 'private';
+
+CREATE INDEX IF NOT EXISTS idx_events_visibility ON events(visibility);
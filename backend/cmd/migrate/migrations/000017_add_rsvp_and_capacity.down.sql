@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_attendees_event_status;
+DROP INDEX IF EXISTS idx_attendees_event_user;
+
+DELETE FROM attendees WHERE status != 'going';
+
+ALTER TABLE attendees DROP COLUMN updated_at;
+ALTER TABLE attendees DROP COLUMN status;
+ALTER TABLE events DROP COLUMN capacity;
@@ -0,0 +1,13 @@
+-- NULL capacity means unlimited
+ALTER TABLE events ADD COLUMN capacity INTEGER;
+
+ALTER TABLE attendees ADD COLUMN status TEXT NOT NULL DEFAULT 'going';
+ALTER TABLE attendees ADD COLUMN updated_at DATETIME;
+
+UPDATE attendees SET updated_at = datetime('now');
+
+-- Each user has one RSVP per event; drop duplicates added before this was enforced
+DELETE FROM attendees WHERE id NOT IN (SELECT MIN(id) FROM attendees GROUP BY event_id, user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendees_event_user ON attendees(event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_attendees_event_status ON attendees(event_id, status, updated_at);
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_attendees_event_occurrence_status;
+DROP INDEX IF EXISTS idx_attendees_event_occurrence_user;
+
+-- Only whole-event RSVPs survive the downgrade
+DELETE FROM attendees WHERE occurrence != '';
+
+ALTER TABLE attendees DROP COLUMN occurrence;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendees_event_user ON attendees(event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_attendees_event_status ON attendees(event_id, status, updated_at);
+
+DROP TABLE IF EXISTS event_occurrences;
+
+ALTER TABLE events DROP COLUMN exdates;
+ALTER TABLE events DROP COLUMN rrule;
@@ -0,0 +1,27 @@
+-- RFC 5545 RRULE; NULL for one-off events
+ALTER TABLE events ADD COLUMN rrule TEXT;
+-- Comma separated RFC 3339 UTC starts of cancelled occurrences
+ALTER TABLE events ADD COLUMN exdates TEXT;
+
+-- Changes to single occurrences of a recurring event. occurrence is the
+-- original start in RFC 3339 UTC; NULL fields are taken from the series.
+CREATE TABLE IF NOT EXISTS event_occurrences (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    occurrence TEXT NOT NULL,
+    name TEXT,
+    description TEXT,
+    date DATETIME,
+    location TEXT,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_occurrences_event_occurrence ON event_occurrences(event_id, occurrence);
+
+-- RSVPs for a single occurrence; '' for the whole event or series
+ALTER TABLE attendees ADD COLUMN occurrence TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_attendees_event_user;
+DROP INDEX IF EXISTS idx_attendees_event_status;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendees_event_occurrence_user ON attendees(event_id, occurrence, user_id);
+CREATE INDEX IF NOT EXISTS idx_attendees_event_occurrence_status ON attendees(event_id, occurrence, status, updated_at);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_events_starts_at;
+
+ALTER TABLE event_occurrences DROP COLUMN ends_at;
+ALTER TABLE event_occurrences RENAME COLUMN starts_at TO date;
+
+ALTER TABLE events ADD COLUMN date DATETIME;
+UPDATE events SET date = strftime('%Y-%m-%dT%H:%M:%SZ', starts_at);
+
+ALTER TABLE events DROP COLUMN timezone;
+ALTER TABLE events DROP COLUMN ends_at;
+ALTER TABLE events DROP COLUMN starts_at;
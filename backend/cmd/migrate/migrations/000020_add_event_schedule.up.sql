@@ -0,0 +1,32 @@
+-- Events start and end at UTC instants and take place in an IANA timezone
+ALTER TABLE events ADD COLUMN starts_at DATETIME;
+ALTER TABLE events ADD COLUMN ends_at DATETIME;
+ALTER TABLE events ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+-- date was written in several formats over time. Convert each to UTC:
+-- DD-MM-YYYY, Go's time.String() ("2006-01-02 15:04:05.999 -0700 MST") and
+-- whatever SQLite understands (YYYY-MM-DD, RFC 3339 with Z or an offset).
+-- Rows matching none stay NULL and fail to load instead of getting a made
+-- up date.
+UPDATE events SET starts_at = CASE
+    WHEN date GLOB '[0-9][0-9]-[0-9][0-9]-[0-9][0-9][0-9][0-9]'
+        THEN datetime(substr(date, 7, 4) || '-' || substr(date, 4, 2) || '-' || substr(date, 1, 2))
+    WHEN date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]* [+-][0-9][0-9][0-9][0-9]*'
+        THEN datetime(substr(date, 1, 19), (
+            SELECT CASE substr(o.offset, 1, 1) WHEN '+' THEN '-' ELSE '+' END
+                || (CAST(substr(o.offset, 2, 2) AS INTEGER) * 60 + CAST(substr(o.offset, 4, 2) AS INTEGER)) || ' minutes'
+            FROM (SELECT substr(date, 20 + instr(substr(date, 20), ' '), 5) AS offset) o
+        ))
+    ELSE datetime(date)
+END;
+
+-- Nothing recorded how long events last; assume an hour
+UPDATE events SET ends_at = datetime(starts_at, '+1 hour') WHERE starts_at IS NOT NULL;
+
+ALTER TABLE events DROP COLUMN date;
+
+-- Single occurrences can be moved and made longer or shorter
+ALTER TABLE event_occurrences RENAME COLUMN date TO starts_at;
+ALTER TABLE event_occurrences ADD COLUMN ends_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_events_starts_at ON events(starts_at);
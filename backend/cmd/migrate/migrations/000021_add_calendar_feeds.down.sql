@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_calendar_cancellations_user_id;
+DROP TABLE IF EXISTS calendar_cancellations;
+DROP TABLE IF EXISTS calendar_feeds;
+
+ALTER TABLE events DROP COLUMN updated_at;
+ALTER TABLE events DROP COLUMN sequence;
@@ -0,0 +1,31 @@
+-- SEQUENCE numbers for iCalendar clients; updated_at doubles as DTSTAMP
+ALTER TABLE events ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN updated_at DATETIME;
+UPDATE events SET updated_at = CURRENT_TIMESTAMP;
+
+-- One secret feed URL per user; only the token's hash is stored
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL UNIQUE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Deleted events stay in the feeds of the people who had them, as
+-- cancelled, so calendar clients remove them
+CREATE TABLE IF NOT EXISTS calendar_cancellations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    event_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    timezone TEXT NOT NULL,
+    sequence INTEGER NOT NULL,
+    cancelled_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_cancellations_user_id ON calendar_cancellations(user_id, cancelled_at);
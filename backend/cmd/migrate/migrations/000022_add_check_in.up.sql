@@ -0,0 +1,11 @@
+-- Door check-ins, one per RSVP and occurrence, so a ticket for a whole
+-- series works once at every occurrence. occurrence is '' for one-off
+-- events.
+CREATE TABLE IF NOT EXISTS check_ins (
+    attendee_id INTEGER NOT NULL,
+    occurrence TEXT NOT NULL DEFAULT '',
+    checked_in_at DATETIME NOT NULL,
+    checked_in_by INTEGER,
+    PRIMARY KEY (attendee_id, occurrence),
+    FOREIGN KEY (attendee_id) REFERENCES attendees(id) ON DELETE CASCADE
+);
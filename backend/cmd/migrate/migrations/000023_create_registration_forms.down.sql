@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_registration_answers_field_id;
+DROP INDEX IF EXISTS idx_registration_answers_attendee_field;
+DROP TABLE IF EXISTS registration_answers;
+
+DROP INDEX IF EXISTS idx_registration_fields_event_id;
+DROP TABLE IF EXISTS registration_fields;
@@ -0,0 +1,29 @@
+-- Questions organizers ask at sign-up. options is a JSON array of the
+-- choices of single_choice and multi_choice fields.
+CREATE TABLE IF NOT EXISTS registration_fields (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('text', 'number', 'boolean', 'single_choice', 'multi_choice')),
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    options TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_registration_fields_event_id ON registration_fields(event_id);
+
+-- Answers belong to an RSVP; value is JSON
+CREATE TABLE IF NOT EXISTS registration_answers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    attendee_id INTEGER NOT NULL,
+    field_id INTEGER NOT NULL,
+    value TEXT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (attendee_id) REFERENCES attendees(id) ON DELETE CASCADE,
+    FOREIGN KEY (field_id) REFERENCES registration_fields(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_registration_answers_attendee_field ON registration_answers(attendee_id, field_id);
+CREATE INDEX IF NOT EXISTS idx_registration_answers_field_id ON registration_answers(field_id);
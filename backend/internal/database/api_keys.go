@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// API key scopes. A read-only key can call every GET endpoint its owner can;
+// writes need a read-write key.
+const (
+	APIKeyScopeRead      = "read"
+	APIKeyScopeReadWrite = "read_write"
+)
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// APIKey is a long-lived credential for scripts. Like refresh tokens only the
+// SHA-256 hash is stored; Prefix is enough of the plaintext to recognise it.
+type APIKey struct {
+	Id         int        `json:"id"`
+	UserId     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scope      string     `json:"scope"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Expired reports whether the key is past its expiry at now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+func (m *APIKeyModel) Insert(key *APIKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	key.CreatedAt = time.Now().UTC()
+	var expiresAt *time.Time
+	if key.ExpiresAt != nil {
+		utc := key.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scope, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	return m.DB.QueryRowContext(ctx, query, key.UserId, key.Name, key.Prefix, key.KeyHash, key.Scope, expiresAt, key.CreatedAt).Scan(&key.Id)
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scope, expires_at, last_used_at, created_at"
+
+func scanAPIKey(scan func(dest ...any) error) (*APIKey, error) {
+	var key APIKey
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &key.KeyHash, &key.Scope, &expiresAt, &lastUsedAt, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	return &key, nil
+}
+
+// GetByHash returns the key with the given hash, or nil if there is none.
+func (m *APIKeyModel) GetByHash(hash string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = $1"
+	key, err := scanAPIKey(m.DB.QueryRowContext(ctx, query, hash).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAllByUser lists the user's keys, newest first.
+func (m *APIKeyModel) GetAllByUser(userId int) ([]*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE user_id = $1 ORDER BY id DESC"
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Touch records that the key was just used.
+func (m *APIKeyModel) Touch(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", time.Now().UTC(), id)
+	return err
+}
+
+// Delete removes one of the user's keys. It returns sql.ErrNoRows when the
+// user has no key with that id.
+func (m *APIKeyModel) Delete(ctx context.Context, userId, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
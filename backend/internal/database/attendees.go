@@ -3,120 +3,813 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 )
 
+// Attendee statuses. Only going attendees count towards an event's capacity;
+// waitlisted ones are promoted in the order they joined the waitlist. Each
+// occurrence of a recurring event has its own capacity, and so do RSVPs for
+// the whole series.
+const (
+	AttendeeGoing      = "going"
+	AttendeeWaitlisted = "waitlisted"
+	AttendeeDeclined   = "declined"
+	AttendeeCancelled  = "cancelled"
+)
+
+// Check-in errors. Handlers answer ErrTicketNotFound with 404,
+// ErrTicketNotGoing with 409 and ErrAlreadyCheckedIn with 409 as well.
+var (
+	ErrTicketNotFound   = errors.New("ticket not found")
+	ErrTicketNotGoing   = errors.New("ticket holder is not going")
+	ErrAlreadyCheckedIn = errors.New("ticket already checked in")
+)
+
+// ErrAlreadyGoing is returned when admitting someone who is already going.
+var ErrAlreadyGoing = errors.New("already going")
+
 type AttendeeModel struct {
-	DB *sql.DB 
+	DB *sql.DB
 }
 
-type Attendee struct{
-	Id int `json:"id"`
-	UserId int `json:"userId"`
-	EventId int `json:"eventId"`
+// Attendee is a user's RSVP. Occurrence is the OccurrenceKey of the
+// occurrence of a recurring event it is for, or "" when it is for the whole
+// event or series.
+type Attendee struct {
+	Id         int       `json:"id"`
+	UserId     int       `json:"userId"`
+	EventId    int       `json:"eventId"`
+	Occurrence string    `json:"occurrence,omitempty"`
+	Status     string    `json:"status"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// CheckedInAt is set when the attendee's ticket was scanned at the door
+	// of Occurrence, by the organizer CheckedInBy. Check-ins of a series
+	// RSVP at single occurrences are only in CheckInStats.
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInBy *int       `json:"checked_in_by,omitempty"`
 }
 
-func (m *AttendeeModel) Insert(attendee *Attendee) (*Attendee, error){
-	ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+// attendeeColumns are read from attendeeFrom, which joins in the RSVP's
+// check-in for its own occurrence.
+const (
+	attendeeColumns = "a.id, a.user_id, a.event_id, a.occurrence, a.status, a.updated_at, c.checked_in_at, c.checked_in_by"
+	attendeeFrom    = "attendees a LEFT JOIN check_ins c ON c.attendee_id = a.id AND c.occurrence = a.occurrence"
+)
+
+func scanAttendee(scan func(dest ...any) error) (*Attendee, error) {
+	var a Attendee
+	var updatedAt, checkedInAt sql.NullTime
+	var checkedInBy sql.NullInt64
+	if err := scan(&a.Id, &a.UserId, &a.EventId, &a.Occurrence, &a.Status, &updatedAt, &checkedInAt, &checkedInBy); err != nil {
+		return nil, err
+	}
+	a.UpdatedAt = updatedAt.Time
+	if checkedInAt.Valid {
+		a.CheckedInAt = &checkedInAt.Time
+	}
+	if checkedInBy.Valid {
+		by := int(checkedInBy.Int64)
+		a.CheckedInBy = &by
+	}
+	return &a, nil
+}
+
+// Insert adds a going attendee, regardless of the event's capacity.
+func (m *AttendeeModel) Insert(attendee *Attendee) (*Attendee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := "INSERT INTO attendees (user_id, event_id) VALUES ($1, $2) RETURNING id"
+	attendee.Status = AttendeeGoing
+	attendee.UpdatedAt = time.Now().UTC()
+
+	query := "INSERT INTO attendees (user_id, event_id, occurrence, status, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
 
-	err := m.DB.QueryRowContext(ctx, query, attendee.UserId, attendee.EventId).Scan(&attendee.Id)
+	err := m.DB.QueryRowContext(ctx, query, attendee.UserId, attendee.EventId, attendee.Occurrence, attendee.Status, attendee.UpdatedAt).Scan(&attendee.Id)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return attendee, nil 
+	return attendee, nil
 }
 
-func (m *AttendeeModel) GetByEventAndAttendee(eventId, userId int) (*Attendee, error){
-	ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+// GetByEventAndAttendee returns the user's RSVP for the event, or for one
+// of its occurrences, in any status.
+func (m *AttendeeModel) GetByEventAndAttendee(eventId int, occurrence string, userId int) (*Attendee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := "SELECT * FROM attendees WHERE event_id = $1 AND user_id = $2"
+	attendee, err := getAttendee(ctx, m.DB, eventId, occurrence, userId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return attendee, err
+}
+
+func getAttendee(ctx context.Context, q execQueryer, eventId int, occurrence string, userId int) (*Attendee, error) {
+	query := "SELECT " + attendeeColumns + " FROM " + attendeeFrom + " WHERE a.event_id = $1 AND a.occurrence = $2 AND a.user_id = $3"
+	return scanAttendee(q.QueryRowContext(ctx, query, eventId, occurrence, userId).Scan)
+}
+
+// CheckIn records that the holder of ticket attendeeId arrived at event
+// eventId, or at its occurrence occurrence, scanned by organizer by. A
+// ticket works once per occurrence: later scans get ErrAlreadyCheckedIn
+// along with the attendee, so staff can see when it was used. Only going
+// attendees can check in. A ticket for a whole series admits to each of its
+// occurrences, unless the holder answered that occurrence on its own; then
+// that answer counts.
+func (m *AttendeeModel) CheckIn(ctx context.Context, eventId, attendeeId int, occurrence string, by int) (*Attendee, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	// With immediate transactions two scanners racing on the same ticket
+	// cannot both succeed
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := "SELECT " + attendeeColumns + " FROM " + attendeeFrom + " WHERE a.id = $1 AND a.event_id = $2"
+	attendee, err := scanAttendee(tx.QueryRowContext(ctx, query, attendeeId, eventId).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case occurrence == "":
+		occurrence = attendee.Occurrence
+	case attendee.Occurrence == "":
+		own, err := getAttendee(ctx, tx, eventId, occurrence, attendee.UserId)
+		if err == nil {
+			attendee = own
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+	case attendee.Occurrence != occurrence:
+		return nil, ErrTicketNotFound
+	}
+	if attendee.Status != AttendeeGoing {
+		return attendee, ErrTicketNotGoing
+	}
 
-	var attendee Attendee 
-	err := m.DB.QueryRowContext(ctx, query, eventId, userId).Scan(&attendee.Id, &attendee.UserId, &attendee.EventId)
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, "INSERT INTO check_ins (attendee_id, occurrence, checked_in_at, checked_in_by) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+		attendee.Id, occurrence, now, by)
 	if err != nil {
-		if err == sql.ErrNoRows{
-			return nil, nil 
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		var checkedInAt time.Time
+		var checkedInBy sql.NullInt64
+		query = "SELECT checked_in_at, checked_in_by FROM check_ins WHERE attendee_id = $1 AND occurrence = $2"
+		if err := tx.QueryRowContext(ctx, query, attendee.Id, occurrence).Scan(&checkedInAt, &checkedInBy); err != nil {
+			return nil, err
 		}
-		return nil, err 
+		attendee.CheckedInAt = &checkedInAt
+		if checkedInBy.Valid {
+			by := int(checkedInBy.Int64)
+			attendee.CheckedInBy = &by
+		}
+		return attendee, ErrAlreadyCheckedIn
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	attendee.CheckedInAt = &now
+	attendee.CheckedInBy = &by
+	return attendee, nil
+}
+
+// deleteCheckIns removes the check-ins of the RSVPs matching where (on
+// attendees), before the RSVPs themselves go.
+func deleteCheckIns(ctx context.Context, q execQueryer, where string, args ...any) error {
+	_, err := q.ExecContext(ctx, "DELETE FROM check_ins WHERE attendee_id IN (SELECT id FROM attendees WHERE "+where+")", args...)
+	return err
+}
+
+// CheckInStats counts the people going to an event, or one occurrence of
+// it, and how many of them have arrived. People going to the whole series
+// count at each occurrence they did not answer on its own.
+type CheckInStats struct {
+	Registered    int        `json:"registered"`
+	CheckedIn     int        `json:"checked_in"`
+	NotCheckedIn  int        `json:"not_checked_in"`
+	LastCheckInAt *time.Time `json:"last_check_in_at"`
+}
+
+func (m *AttendeeModel) CheckInStats(eventId int, occurrence string) (*CheckInStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT COUNT(*), COUNT(c.checked_in_at), MAX(c.checked_in_at)
+	FROM attendees a LEFT JOIN check_ins c ON c.attendee_id = a.id AND c.occurrence = $2
+	WHERE a.event_id = $1 AND a.status = $3 AND ` + atOccurrence("$2")
+
+	var stats CheckInStats
+	var last sql.NullString
+	if err := m.DB.QueryRowContext(ctx, query, eventId, occurrence, AttendeeGoing).Scan(&stats.Registered, &stats.CheckedIn, &last); err != nil {
+		return nil, err
+	}
+	stats.NotCheckedIn = stats.Registered - stats.CheckedIn
+	if t, err := parseStoredTime(last); err == nil {
+		stats.LastCheckInAt = &t
+	}
+	return &stats, nil
+}
+
+// HasRSVP reports whether the user is going to the event or any of its
+// occurrences, or on a waitlist. People who declined or cancelled no longer
+// count as guests.
+func (m *AttendeeModel) HasRSVP(eventId, userId int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM attendees WHERE event_id = $1 AND user_id = $2 AND status IN ('going', 'waitlisted'))", eventId, userId).Scan(&exists)
+	return exists, err
+}
+
+func setAttendeeStatus(ctx context.Context, q execQueryer, id int, status string) error {
+	_, err := q.ExecContext(ctx, "UPDATE attendees SET status = $1, updated_at = $2 WHERE id = $3", status, time.Now().UTC(), id)
+	return err
+}
+
+// Admit makes the user going to the event, or to one occurrence of it,
+// without looking at its capacity, and saves their answers to the
+// registration form in the same transaction. Organizers use it to add
+// people directly, including from the waitlist. Users who are already going
+// get ErrAlreadyGoing.
+func (m *AttendeeModel) Admit(ctx context.Context, eventId int, occurrence string, userId int, answers Answers) (*Attendee, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	attendee, err := getAttendee(ctx, tx, eventId, occurrence, userId)
+	switch {
+	case err == sql.ErrNoRows:
+		attendee = &Attendee{UserId: userId, EventId: eventId, Occurrence: occurrence, Status: AttendeeGoing, UpdatedAt: time.Now().UTC()}
+		err = tx.QueryRowContext(ctx, "INSERT INTO attendees (user_id, event_id, occurrence, status, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			userId, eventId, occurrence, attendee.Status, attendee.UpdatedAt).Scan(&attendee.Id)
+	case err != nil:
+	case attendee.Status == AttendeeGoing:
+		return nil, ErrAlreadyGoing
+	default:
+		attendee.Status = AttendeeGoing
+		err = setAttendeeStatus(ctx, tx, attendee.Id, AttendeeGoing)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return &attendee, nil
+	if err := saveAnswers(ctx, tx, attendee.Id, answers); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return attendee, nil
 }
 
-func (m *AttendeeModel) GetAttendeesByEvent(eventId int) ([]*User, error){
-	ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+// Respond records the user's answer to an event, or to one occurrence of it
+// when occurrence is not "": AttendeeGoing,
+// AttendeeDeclined or AttendeeCancelled. Going users join the waitlist when
+// the event is full, and stay where they are if they already said yes.
+// When a going user backs out, waitlisted users are promoted into the freed
+// spots and returned. Everything happens in one transaction; with the
+// connection's immediate transactions that keeps concurrent RSVPs from
+// overfilling the event. Cancelling without an RSVP returns sql.ErrNoRows.
+// Answers to the event's registration form, already checked, are saved with
+// the RSVP.
+func (m *AttendeeModel) Respond(ctx context.Context, eventId int, occurrence string, userId int, status string, answers Answers) (attendee *Attendee, promoted []*Attendee, err error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
-	query := `
-	SELECT u.id, u.name, u.email
-	FROM users u 
-	JOIN attendees a ON u.id = a.user_id 
-	WHERE a.event_id = $1 
-	`
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
 
-	rows, err := m.DB.QueryContext(ctx, query, eventId)
+	attendee, promoted, err = respond(ctx, tx, eventId, occurrence, userId, status)
 	if err != nil {
-		return nil, err 
+		return nil, nil, err
+	}
+	if err := saveAnswers(ctx, tx, attendee.Id, answers); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return attendee, promoted, nil
+}
+
+// respond is Respond inside the caller's transaction.
+func respond(ctx context.Context, q execQueryer, eventId int, occurrence string, userId int, status string) (attendee *Attendee, promoted []*Attendee, err error) {
+	existing, err := getAttendee(ctx, q, eventId, occurrence, userId)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+
+	switch {
+	case existing == nil && status == AttendeeCancelled:
+		return nil, nil, sql.ErrNoRows
+	case existing != nil && existing.Status == status:
+		return existing, nil, nil
+	case existing != nil && existing.Status == AttendeeWaitlisted && status == AttendeeGoing:
+		return existing, nil, nil
+	}
+
+	if status == AttendeeGoing {
+		free, err := freeSpots(ctx, q, eventId, occurrence)
+		if err != nil {
+			return nil, nil, err
+		}
+		if free == 0 {
+			status = AttendeeWaitlisted
+		}
+	}
+
+	previous := ""
+	if existing != nil {
+		previous = existing.Status
+	}
+
+	now := time.Now().UTC()
+	if existing == nil {
+		attendee = &Attendee{UserId: userId, EventId: eventId, Occurrence: occurrence, Status: status, UpdatedAt: now}
+		query := "INSERT INTO attendees (user_id, event_id, occurrence, status, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+		if err := q.QueryRowContext(ctx, query, userId, eventId, occurrence, status, now).Scan(&attendee.Id); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if err := setAttendeeStatus(ctx, q, existing.Id, status); err != nil {
+			return nil, nil, err
+		}
+		attendee = existing
+		attendee.Status, attendee.UpdatedAt = status, now
+	}
+
+	// Leaving one occurrence can also free the spot a series RSVP held there
+	if status != AttendeeGoing && (previous == AttendeeGoing || occurrence != "") {
+		if promoted, err = promoteWaitlisted(ctx, q, eventId, occurrence); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return attendee, promoted, nil
+}
+
+// atOccurrence is the condition for the attendees a who count at the
+// occurrence given by the placeholder occurrenceArg: the RSVPs for that
+// occurrence, and the series RSVPs of people without an RSVP of their own
+// for it. With the series itself, the empty occurrence, it matches the
+// series RSVPs.
+func atOccurrence(occurrenceArg string) string {
+	return `(a.occurrence = ` + occurrenceArg + ` OR (a.occurrence = '' AND NOT EXISTS (
+		SELECT 1 FROM attendees o WHERE o.event_id = a.event_id AND o.user_id = a.user_id AND o.occurrence = ` + occurrenceArg + `)))`
+}
+
+// freeSpots returns how many more people can go to the event occurrence, or
+// -1 when its capacity is unlimited. People going to the whole series take a
+// spot at every occurrence.
+func freeSpots(ctx context.Context, q execQueryer, eventId int, occurrence string) (int, error) {
+	var capacity sql.NullInt64
+	if err := q.QueryRowContext(ctx, "SELECT capacity FROM events WHERE id = $1", eventId).Scan(&capacity); err != nil {
+		return 0, err
+	}
+	if !capacity.Valid {
+		return -1, nil
+	}
+
+	var going int
+	query := "SELECT COUNT(*) FROM attendees a WHERE a.event_id = $1 AND a.status = 'going' AND " + atOccurrence("$2")
+	if err := q.QueryRowContext(ctx, query, eventId, occurrence).Scan(&going); err != nil {
+		return 0, err
+	}
+	return max(int(capacity.Int64)-going, 0), nil
+}
+
+// promoteWaitlisted moves the longest waiting people into the free spots of
+// the event occurrence and returns them. A spot freed in the whole series
+// (the empty occurrence) is free at every occurrence too, so then their
+// waitlists move up as well.
+func promoteWaitlisted(ctx context.Context, q execQueryer, eventId int, occurrence string) ([]*Attendee, error) {
+	promoted, err := promoteWaitlist(ctx, q, eventId, occurrence)
+	if err != nil || occurrence != "" {
+		return promoted, err
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT DISTINCT occurrence FROM attendees WHERE event_id = $1 AND occurrence != '' AND status = 'waitlisted' ORDER BY occurrence", eventId)
+	if err != nil {
+		return nil, err
+	}
+	var occurrences []string
+	for rows.Next() {
+		var occurrence string
+		if err := rows.Scan(&occurrence); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
 
-	var users []*User 
+	for _, occurrence := range occurrences {
+		p, err := promoteWaitlist(ctx, q, eventId, occurrence)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, p...)
+	}
+	return promoted, nil
+}
 
-	for rows.Next(){
-		var user User 
-		err := rows.Scan(&user.Id, &user.Name, &user.Email)
+// promoteWaitlist is promoteWaitlisted for the waitlist of a single
+// occurrence, or of the series.
+func promoteWaitlist(ctx context.Context, q execQueryer, eventId int, occurrence string) ([]*Attendee, error) {
+	free, err := freeSpots(ctx, q, eventId, occurrence)
+	if err != nil || free == 0 {
+		return nil, err
+	}
+
+	query := "SELECT " + attendeeColumns + " FROM " + attendeeFrom + " WHERE a.event_id = $1 AND a.occurrence = $2 AND a.status = 'waitlisted' ORDER BY a.updated_at, a.id"
+	if free > 0 {
+		query += fmt.Sprintf(" LIMIT %d", free)
+	}
+
+	rows, err := q.QueryContext(ctx, query, eventId, occurrence)
+	if err != nil {
+		return nil, err
+	}
+	var promoted []*Attendee
+	for rows.Next() {
+		a, err := scanAttendee(rows.Scan)
 		if err != nil {
-			return nil, err 
+			rows.Close()
+			return nil, err
+		}
+		promoted = append(promoted, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range promoted {
+		if err := setAttendeeStatus(ctx, q, a.Id, AttendeeGoing); err != nil {
+			return nil, err
 		}
-		users = append(users, &user)
+		a.Status = AttendeeGoing
 	}
-	return users, nil
+	return promoted, nil
 }
 
-func (m *AttendeeModel) DeleteByEventAndUser(userId, eventId int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+// PromoteWaitlisted fills any free spots of the event and its occurrences
+// from their waitlists, for instance after its capacity was raised, and
+// returns who got in.
+func (m *AttendeeModel) PromoteWaitlisted(ctx context.Context, eventId int) ([]*Attendee, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
-	query := "DELETE FROM attendees WHERE user_id = $1 AND event_id = $2"
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	promoted, err := promoteWaitlisted(ctx, tx, eventId, "")
+	if err != nil {
+		return nil, err
+	}
+	return promoted, tx.Commit()
+}
+
+// attendeeListSpec is how an event's attendee list can be sorted and
+// filtered. "joined" is the order people were added in. The occurrence
+// filter picks one occurrence of a recurring event.
+var attendeeListSpec = listSpec{
+	sorts: map[string]string{
+		"joined": "a.id",
+		"name":   "u.name COLLATE NOCASE",
+		"email":  "u.email",
+	},
+	defaultSort: "joined",
+	filters: map[string]listFilter{
+		"q":          containsFilter("u.name", "u.email"),
+		"name":       containsFilter("u.name"),
+		"email":      containsFilter("u.email"),
+		"status":     statusFilter,
+		"occurrence": occurrenceFilter,
+		"checked_in": checkedInFilter,
+	},
+	idColumn: "a.id",
+}
+
+// AttendeeFilters are the filter names attendee lists accept.
+var AttendeeFilters = sortedKeys(attendeeListSpec.filters)
 
-	_, err := m.DB.ExecContext(ctx, query, userId, eventId)
+func statusFilter(value string, arg func(any) string) (string, error) {
+	switch value {
+	case AttendeeGoing, AttendeeWaitlisted, AttendeeDeclined, AttendeeCancelled:
+		return "a.status = " + arg(value), nil
+	}
+	return "", fmt.Errorf("must be one of going, waitlisted, declined, cancelled")
+}
+
+func occurrenceFilter(value string, arg func(any) string) (string, error) {
+	t, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		return err 
+		return "", fmt.Errorf("use the RFC3339 start of the occurrence")
 	}
-	return nil
+	return "a.occurrence = " + arg(OccurrenceKey(t)), nil
+}
+
+func checkedInFilter(value string, arg func(any) string) (string, error) {
+	switch value {
+	case "true":
+		return "c.checked_in_at IS NOT NULL", nil
+	case "false":
+		return "c.checked_in_at IS NULL", nil
+	}
+	return "", fmt.Errorf("must be true or false")
+}
+
+// GetAttendeesByEvent lists the users going to the event, or those with the
+// status given by the "status" filter. Unless the "occurrence" filter is
+// given, only RSVPs for the whole event or series are listed.
+func (m *AttendeeModel) GetAttendeesByEvent(eventId int, params ListParams) (*Page[*User], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return runList(ctx, m.DB, attendeeListSpec, listQuery{
+		columns: "u.id, u.name, u.email",
+		from:    attendeeListFrom,
+		where:   attendeeListWhere(params),
+		args:    []any{eventId},
+	}, params, func(scan func(dest ...any) error) (*User, bool, error) {
+		var user User
+		if err := scan(&user.Id, &user.Name, &user.Email); err != nil {
+			return nil, false, err
+		}
+		return &user, true, nil
+	})
+}
+
+// attendeeListFrom joins attendee lists with users and with each RSVP's
+// check-in for its own occurrence.
+const attendeeListFrom = "users u JOIN attendees a ON u.id = a.user_id LEFT JOIN check_ins c ON c.attendee_id = a.id AND c.occurrence = a.occurrence"
+
+// attendeeListWhere is the conditions of an attendee list of event $1:
+// people going to the whole event, unless the filters ask otherwise.
+func attendeeListWhere(params ListParams) []string {
+	where := []string{"a.event_id = $1"}
+	if _, ok := params.Filters["status"]; !ok {
+		where = append(where, "a.status = 'going'")
+	}
+	if _, ok := params.Filters["occurrence"]; !ok {
+		where = append(where, "a.occurrence = ''")
+	}
+	return where
+}
+
+// ExportRow is one line of an attendee export.
+type ExportRow struct {
+	AttendeeId  int
+	UserId      int
+	Name        string
+	Email       string
+	Occurrence  string
+	Status      string
+	RespondedAt time.Time
+	CheckedInAt *time.Time
+}
+
+// Export calls fn for each attendee of the event matching params, in list
+// order, reading them from the database a page at a time. The list filters
+// and sorts apply as in GetAttendeesByEvent; paging does not.
+func (m *AttendeeModel) Export(ctx context.Context, eventId int, params ListParams, fn func(*ExportRow) error) error {
+	return streamList(ctx, m.DB, attendeeListSpec, listQuery{
+		columns: "a.id, u.id, u.name, u.email, a.occurrence, a.status, a.updated_at, c.checked_in_at",
+		from:    attendeeListFrom,
+		where:   attendeeListWhere(params),
+		args:    []any{eventId},
+	}, params, func(scan func(dest ...any) error) (*ExportRow, error) {
+		var row ExportRow
+		var respondedAt, checkedInAt sql.NullTime
+		if err := scan(&row.AttendeeId, &row.UserId, &row.Name, &row.Email, &row.Occurrence, &row.Status, &respondedAt, &checkedInAt); err != nil {
+			return nil, err
+		}
+		row.RespondedAt = respondedAt.Time
+		if checkedInAt.Valid {
+			row.CheckedInAt = &checkedInAt.Time
+		}
+		return &row, nil
+	}, fn)
 }
 
-func (m *AttendeeModel) GetEventsByAttendee(attendeeId int) ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+// DeleteByEventAndUser removes the user's RSVP for one occurrence, or all of
+// their RSVPs for the event when occurrence is "". Spots they had go to the
+// waitlists; the promoted attendees are returned.
+func (m *AttendeeModel) DeleteByEventAndUser(userId, eventId int, occurrence string) ([]*Attendee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := `
-	SELECT e.id, e.owner_id, e.name, e.description, e.date, e.location
-	FROM events e
-	JOIN attendees a ON e.id = a.event_id
-	WHERE a.user_id = $1
-	`
-	rows, err := m.DB.QueryContext(ctx, query, attendeeId)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err 
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	defer rows.Close()
+	where := "user_id = $1 AND event_id = $2"
+	args := []any{userId, eventId}
+	if occurrence != "" {
+		where += " AND occurrence = $3"
+		args = append(args, occurrence)
+	}
+	if err := deleteAnswers(ctx, tx, where, args...); err != nil {
+		return nil, err
+	}
+	if err := deleteCheckIns(ctx, tx, where, args...); err != nil {
+		return nil, err
+	}
+	query := "DELETE FROM attendees WHERE " + where + " RETURNING event_id, occurrence, status"
 
-	var events []*Event
-	for rows.Next(){
-		var event Event 
-		err := rows.Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &event.Date, &event.Location)
+	freed, err := collectFreed(tx.QueryContext(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []*Attendee
+	for _, f := range freed {
+		p, err := promoteWaitlisted(ctx, tx, f.eventId, f.occurrence)
 		if err != nil {
 			return nil, err
 		}
-		events = append(events, &event)
+		promoted = append(promoted, p...)
+	}
+	return promoted, tx.Commit()
+}
+
+// freedSpot is an event occurrence where a going attendee was removed.
+type freedSpot struct {
+	eventId    int
+	occurrence string
+}
+
+// collectFreed reads the rows of an attendee DELETE ... RETURNING event_id,
+// occurrence, status and returns the distinct spots that were freed.
+func collectFreed(rows *sql.Rows, err error) ([]freedSpot, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var freed []freedSpot
+	for rows.Next() {
+		var f freedSpot
+		var status string
+		if err := rows.Scan(&f.eventId, &f.occurrence, &status); err != nil {
+			return nil, err
+		}
+		if status == AttendeeGoing && !slices.Contains(freed, f) {
+			freed = append(freed, f)
+		}
+	}
+	return freed, rows.Err()
+}
+
+// GetEventsByAttendee lists the events the user is going to, or going to at
+// least one occurrence of. With viewerId set only the events that user may
+// see in a list are included (see viewableBy); nil lists them all.
+func (m *AttendeeModel) GetEventsByAttendee(attendeeId int, viewerId *int, params ListParams) (*Page[*Event], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	q := listQuery{
+		columns: eventColumns("e"),
+		from:    "events e",
+		where:   []string{"EXISTS (SELECT 1 FROM attendees a WHERE a.event_id = e.id AND a.user_id = $1 AND a.status = 'going')"},
+		args:    []any{attendeeId},
+	}
+	if viewerId != nil {
+		q.where = append(q.where, viewableBy("$2"))
+		q.args = append(q.args, *viewerId)
+	}
+	return runList(ctx, m.DB, eventListSpec, q, params, scanListedEvent)
+}
+
+// Outcomes of an attendee import row.
+const (
+	ImportAdded     = "added"
+	ImportDuplicate = "duplicate"
+	ImportUnknown   = "unknown"
+	ImportInvalid   = "invalid"
+)
+
+// importTimeout bounds an attendee import, which looks up every row in one
+// transaction.
+var importTimeout = 30 * time.Second
+
+// ImportRow is one row of an attendee import: a user given by Email or
+// UserId, and what became of it. Rows the caller found invalid come with
+// Result already set to ImportInvalid and are left alone.
+type ImportRow struct {
+	Line   int    `json:"line"`
+	Value  string `json:"value"`
+	Email  string `json:"-"`
+	UserId int    `json:"user_id,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Import adds the users in rows to the event, or to one occurrence of it,
+// like organizers adding them one by one: past the capacity, and moving
+// anyone waitlisted, declined or cancelled to going. Each row's Result says
+// whether the user was added, is unknown, or is a duplicate, because they
+// are already going or appeared on an earlier row. Emails match
+// regardless of case. Everything happens in one transaction; with dryRun it
+// is rolled back, so the report shows what a real import would do.
+func (m *AttendeeModel) Import(ctx context.Context, eventId int, occurrence string, rows []*ImportRow, dryRun bool) error {
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seen := map[int]int{}
+	for _, row := range rows {
+		if row.Result == ImportInvalid {
+			continue
+		}
+
+		var user *User
+		if row.UserId != 0 {
+			user, err = scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", row.UserId).Scan)
+		} else {
+			// An exact match wins over one differing only in case
+			query := "SELECT " + userColumns + " FROM users WHERE email = $1 COLLATE NOCASE ORDER BY email = $1 DESC LIMIT 1"
+			user, err = scanUser(tx.QueryRowContext(ctx, query, row.Email).Scan)
+		}
+		switch {
+		case err == sql.ErrNoRows:
+			row.Result, row.Error = ImportUnknown, "no user with this email address or ID"
+			continue
+		case err != nil:
+			return err
+		case user.Disabled():
+			row.Result, row.Error = ImportInvalid, "the account is disabled"
+			continue
+		}
+		row.UserId = user.Id
+
+		if line, ok := seen[user.Id]; ok {
+			row.Result, row.Error = ImportDuplicate, fmt.Sprintf("same user as line %d", line)
+			continue
+		}
+		seen[user.Id] = row.Line
+
+		existing, err := getAttendee(ctx, tx, eventId, occurrence, user.Id)
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.ExecContext(ctx, "INSERT INTO attendees (user_id, event_id, occurrence, status, updated_at) VALUES ($1, $2, $3, $4, $5)",
+				user.Id, eventId, occurrence, AttendeeGoing, time.Now().UTC())
+		case err != nil:
+		case existing.Status == AttendeeGoing:
+			row.Result, row.Error = ImportDuplicate, "already going"
+			continue
+		default:
+			err = setAttendeeStatus(ctx, tx, existing.Id, AttendeeGoing)
+		}
+		if err != nil {
+			return err
+		}
+		row.Result = ImportAdded
 	}
-	return events, nil 
-}
\ No newline at end of file
+
+	if dryRun {
+		return nil
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+// AuditEntry records a security-relevant event. Details is stored as JSON.
+type AuditEntry struct {
+	Id        int            `json:"id"`
+	Action    string         `json:"action"`
+	UserId    *int           `json:"user_id,omitempty"`
+	IPAddress string         `json:"ip_address"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func (m *AuditModel) Insert(entry *AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	entry.CreatedAt = time.Now().UTC()
+	query := `INSERT INTO audit_log (action, user_id, ip_address, details, created_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	return m.DB.QueryRowContext(ctx, query, entry.Action, entry.UserId, entry.IPAddress, string(details), entry.CreatedAt).Scan(&entry.Id)
+}
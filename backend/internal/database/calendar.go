@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+type CalendarModel struct {
+	DB *sql.DB
+}
+
+// CalendarFeed is a user's subscribable calendar. Calendar applications
+// cannot send an Authorization header, so the secret token in the feed URL
+// is the credential; like API keys only its SHA-256 hash is stored.
+type CalendarFeed struct {
+	Id         int        `json:"-"`
+	UserId     int        `json:"-"`
+	TokenHash  string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CalendarCancellation is an event that was deleted while it was in the
+// user's feed. The feed keeps it, as cancelled, so calendar applications
+// remove it instead of keeping a stale copy.
+type CalendarCancellation struct {
+	EventId     int
+	Name        string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Timezone    string
+	Sequence    int
+	CancelledAt time.Time
+}
+
+// SetFeed creates the user's feed, or gives it a new token, which stops the
+// old URL from working.
+func (m *CalendarModel) SetFeed(userId int, tokenHash string) (*CalendarFeed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	feed := &CalendarFeed{UserId: userId, TokenHash: tokenHash, CreatedAt: time.Now().UTC()}
+	query := `INSERT INTO calendar_feeds (user_id, token_hash, created_at) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at, last_used_at = NULL
+	RETURNING id`
+
+	if err := m.DB.QueryRowContext(ctx, query, userId, tokenHash, feed.CreatedAt).Scan(&feed.Id); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+const calendarFeedColumns = "id, user_id, token_hash, created_at, last_used_at"
+
+func scanCalendarFeed(scan func(dest ...any) error) (*CalendarFeed, error) {
+	var feed CalendarFeed
+	var lastUsedAt sql.NullTime
+	if err := scan(&feed.Id, &feed.UserId, &feed.TokenHash, &feed.CreatedAt, &lastUsedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		feed.LastUsedAt = &lastUsedAt.Time
+	}
+	return &feed, nil
+}
+
+// GetFeed returns the user's feed, or nil if they have none.
+func (m *CalendarModel) GetFeed(userId int) (*CalendarFeed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := "SELECT " + calendarFeedColumns + " FROM calendar_feeds WHERE user_id = $1"
+	return scanCalendarFeed(m.DB.QueryRowContext(ctx, query, userId).Scan)
+}
+
+// GetFeedByHash returns the feed with the given token hash, or nil if there
+// is none.
+func (m *CalendarModel) GetFeedByHash(hash string) (*CalendarFeed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := "SELECT " + calendarFeedColumns + " FROM calendar_feeds WHERE token_hash = $1"
+	return scanCalendarFeed(m.DB.QueryRowContext(ctx, query, hash).Scan)
+}
+
+// TouchFeed records that the feed was just fetched.
+func (m *CalendarModel) TouchFeed(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "UPDATE calendar_feeds SET last_used_at = $1 WHERE id = $2", time.Now().UTC(), id)
+	return err
+}
+
+// DeleteFeed turns the user's feed off. It returns sql.ErrNoRows when they
+// had none.
+func (m *CalendarModel) DeleteFeed(ctx context.Context, userId int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM calendar_feeds WHERE user_id = $1", userId)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Cancellations lists the events deleted since since that were in the
+// user's feed.
+func (m *CalendarModel) Cancellations(userId int, since time.Time) ([]*CalendarCancellation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT event_id, name, starts_at, ends_at, timezone, sequence, cancelled_at FROM calendar_cancellations
+	WHERE user_id = $1 AND cancelled_at >= $2 ORDER BY id`
+	rows, err := m.DB.QueryContext(ctx, query, userId, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cancellations := []*CalendarCancellation{}
+	for rows.Next() {
+		var cc CalendarCancellation
+		var startsAt, endsAt sql.NullString
+		if err := rows.Scan(&cc.EventId, &cc.Name, &startsAt, &endsAt, &cc.Timezone, &cc.Sequence, &cc.CancelledAt); err != nil {
+			return nil, err
+		}
+		// Unreadable times only come from legacy rows; such events were
+		// never in a feed either
+		if cc.StartsAt, err = parseStoredTime(startsAt); err != nil {
+			continue
+		}
+		if cc.EndsAt, err = parseStoredTime(endsAt); err != nil {
+			continue
+		}
+		cancellations = append(cancellations, &cc)
+	}
+	return cancellations, rows.Err()
+}
+
+// recordCancellations remembers the events matching where (on events e,
+// with args as $1...) as cancelled for everyone who has them in their feed:
+// their organizers and the people going. It runs before the events are
+// deleted.
+func recordCancellations(ctx context.Context, q execQueryer, where string, args ...any) error {
+	query := `INSERT INTO calendar_cancellations (user_id, event_id, name, starts_at, ends_at, timezone, sequence, cancelled_at)
+	SELECT p.user_id, e.id, e.name, e.starts_at, e.ends_at, e.timezone, e.sequence + 1, $` + strconv.Itoa(len(args)+1) + `
+	FROM events e JOIN (
+		SELECT event_id, user_id FROM event_organizers
+		UNION SELECT event_id, user_id FROM attendees WHERE status = 'going'
+	) p ON p.event_id = e.id
+	WHERE ` + where
+
+	_, err := q.ExecContext(ctx, query, append(args, time.Now().UTC())...)
+	return err
+}
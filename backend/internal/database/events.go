@@ -3,8 +3,12 @@ package database
 import (
 	"context"
 	"database/sql"
-	"database/sql/driver"
+	"errors"
 	"fmt"
+	"log"
+	"rest-api-in-gin/internal/rrule"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -12,67 +16,124 @@ type EventModel struct {
 	DB *sql.DB
 }
 
-// NullableTime handles potentially invalid datetime strings from database
-type NullableTime struct {
-	time.Time
-	Valid bool
+// Event visibility levels. Private events are only visible to their
+// organizers, unlisted ones to anybody who has the link, and public ones are
+// also listed by discovery.
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+// ErrInvalidSchedule is returned for events whose start or end could not be
+// read, which only happens for legacy rows the migration could not convert.
+var ErrInvalidSchedule = errors.New("event has an invalid start or end time")
+
+// ErrVersionConflict is returned by Update when the event was changed after
+// the caller read it. Handlers answer it with 412.
+var ErrVersionConflict = errors.New("event was changed by someone else")
+
+// Event represents an event record in the database.
+type Event struct {
+	Id          int    `json:"id"`
+	OwnerId     int    `json:"ownerId"`
+	Name        string `json:"name" binding:"required,min=3"`
+	Description string `json:"description" binding:"required,min=10"`
+
+	// StartsAt and EndsAt are instants, kept in UTC. Timezone is the IANA
+	// name of the zone the event takes place in; the local times and
+	// recurrences follow it. The local fields are output only.
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	Timezone      string    `json:"timezone"`
+	StartsAtLocal time.Time `json:"starts_at_local"`
+	EndsAtLocal   time.Time `json:"ends_at_local"`
+	// Date is the old name of StartsAt, still read and written for older
+	// clients.
+	Date time.Time `json:"date"`
+
+	Location   string `json:"location" binding:"required,min=3"`
+	Visibility string `json:"visibility" binding:"omitempty,oneof=private unlisted public"`
+	Capacity   *int   `json:"capacity" binding:"omitempty,min=0"` // nil for unlimited
+
+	// RRule makes the event repeat; StartsAt is then the start of the first
+	// occurrence, and every occurrence lasts as long as it. ExDates are the starts of occurrences that were cancelled.
+	RRule   *string     `json:"rrule,omitempty"`
+	ExDates []time.Time `json:"exdates,omitempty"`
+
+	// Sequence counts the changes to the event, for calendar clients; both
+	// it and UpdatedAt are output only.
+	Sequence  int       `json:"sequence"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Version goes up with every write to the event and is its ETag, so
+	// organizers editing at the same time do not overwrite each other.
+	// Output only.
+	Version int `json:"version"`
 }
 
-// Scan implements the Scanner interface for database/sql
-func (nt *NullableTime) Scan(value interface{}) error {
-	if value == nil {
-		nt.Valid = false
-		return nil
+// TimeLocation returns the event's timezone, or UTC when it is not set.
+func (e *Event) TimeLocation() *time.Location {
+	loc, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		return time.UTC
 	}
+	return loc
+}
 
-	switch v := value.(type) {
-	case time.Time:
-		nt.Time = v
-		nt.Valid = true
-		return nil
-	case string:
-		// Try to parse various date formats
-		formats := []string{
-			time.RFC3339,           // "2006-01-02T15:04:05Z07:00"
-			"2006-01-02T15:04:05Z", // "2025-05-20T00:00:00Z"
-			"2006-01-02 15:04:05",  // "2025-01-02 15:04:05"
-			"2006-01-02",           // "2025-01-02"
-			"02-01-2006",           // "01-09-2025"
-		}
+// localize fills in the fields derived from StartsAt, EndsAt and Timezone.
+func (e *Event) localize() {
+	loc := e.TimeLocation()
+	e.StartsAt, e.EndsAt = e.StartsAt.UTC(), e.EndsAt.UTC()
+	e.StartsAtLocal, e.EndsAtLocal = e.StartsAt.In(loc), e.EndsAt.In(loc)
+	e.Date = e.StartsAt
+}
 
-		for _, format := range formats {
-			if t, err := time.Parse(format, v); err == nil {
-				nt.Time = t
-				nt.Valid = true
-				return nil
-			}
-		}
+// Rule parses the event's recurrence rule. It returns nil for one-off events.
+func (e *Event) Rule() (*rrule.Rule, error) {
+	if e.RRule == nil || *e.RRule == "" {
+		return nil, nil
+	}
+	return rrule.Parse(*e.RRule)
+}
 
-		// If all parsing fails, set a default time
-		nt.Time = time.Now()
-		nt.Valid = true
+func (e *Event) rruleValue() any {
+	if e.RRule == nil || *e.RRule == "" {
 		return nil
 	}
+	return *e.RRule
+}
 
-	return fmt.Errorf("cannot scan %T into NullableTime", value)
+// exdatesValue stores ExDates as a sorted, comma separated list of
+// occurrence keys, or NULL when there are none.
+func (e *Event) exdatesValue() any {
+	if len(e.ExDates) == 0 {
+		return nil
+	}
+	keys := make([]string, len(e.ExDates))
+	for i, t := range e.ExDates {
+		keys[i] = OccurrenceKey(t)
+	}
+	slices.Sort(keys)
+	return strings.Join(slices.Compact(keys), ",")
 }
 
-// Value implements the driver Valuer interface
-func (nt NullableTime) Value() (driver.Value, error) {
-	if !nt.Valid {
-		return nil, nil
+func parseExdates(s string) []time.Time {
+	var dates []time.Time
+	for _, key := range strings.Split(s, ",") {
+		if t, err := time.Parse(time.RFC3339, key); err == nil {
+			dates = append(dates, t)
+		}
 	}
-	return nt.Time, nil
+	return dates
 }
 
-// Event represents an event record in the database.
-type Event struct {
-	Id          int       `json:"id"`
-	OwnerId     int       `json:"ownerId"`
-	Name        string    `json:"name" binding:"required,min=3"`
-	Description string    `json:"description" binding:"required,min=10"`
-	Date        time.Time `json:"date" binding:"required"`
-	Location    string    `json:"location" binding:"required,min=3"`
+// capacityValue stores an unset or zero capacity as NULL, meaning unlimited.
+func (e *Event) capacityValue() any {
+	if e.Capacity == nil || *e.Capacity == 0 {
+		return nil
+	}
+	return *e.Capacity
 }
 
 // Insert creates a new event record in the database.
@@ -80,61 +141,113 @@ func (m *EventModel) Insert(event *Event) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := "INSERT INTO events (owner_id, name, description, date, location) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	return m.DB.QueryRowContext(ctx, query, event.OwnerId, event.Name, event.Description, event.Date, event.Location).Scan(&event.Id)
+	if err := insertEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetAll retrieves all events from the database with better error handling
-func (m *EventModel) GetAll() ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// insertEvent is Insert inside the caller's transaction.
+func insertEvent(ctx context.Context, q execQueryer, event *Event) error {
+	if event.Visibility == "" {
+		event.Visibility = VisibilityPrivate
+	}
 
-	query := "SELECT id, owner_id, name, description, date, location FROM events"
+	if event.capacityValue() == nil {
+		event.Capacity = nil
+	}
 
-	rows, err := m.DB.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+	if event.Timezone == "" {
+		event.Timezone = "UTC"
 	}
-	defer rows.Close()
+	event.localize()
+	event.Sequence = 0
+	event.Version = 1
+	event.UpdatedAt = time.Now().UTC()
 
-	events := []*Event{}
+	query := `INSERT INTO events (owner_id, name, description, starts_at, ends_at, timezone, location, visibility, capacity, rrule, exdates, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
 
-	for rows.Next() {
-		var event Event
-		var dateStr string
+	if err := q.QueryRowContext(ctx, query, event.OwnerId, event.Name, event.Description, event.StartsAt, event.EndsAt, event.Timezone, event.Location,
+		event.Visibility, event.capacityValue(), event.rruleValue(), event.exdatesValue(), event.UpdatedAt).Scan(&event.Id); err != nil {
+		return err
+	}
 
-		// Scan date as string first to handle invalid formats
-		if err := rows.Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &dateStr, &event.Location); err != nil {
-			return nil, err
-		}
+	// The creator becomes the owner on the organizer team as well
+	return insertOrganizer(ctx, q, event.Id, event.OwnerId, OrganizerOwner)
+}
 
-		// Try to parse the date string
-		parsedDate, err := parseFlexibleDate(dateStr)
-		if err != nil {
-			fmt.Printf("Warning: Invalid date format for event %d: %s\n", event.Id, dateStr)
-			continue
-		}
+// eventFields are the columns scanEvent expects, in order.
+var eventFields = []string{"id", "owner_id", "name", "description", "starts_at", "ends_at", "timezone", "location", "visibility", "capacity", "rrule", "exdates", "sequence", "updated_at", "version"}
 
-		event.Date = parsedDate
-		events = append(events, &event)
+// eventColumns returns eventFields as a select list, qualified with alias
+// when the query joins other tables.
+func eventColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(eventFields, ", ")
 	}
+	return alias + "." + strings.Join(eventFields, ", "+alias+".")
+}
 
-	if err = rows.Err(); err != nil {
+// scanEvent reads one event row. It returns ErrInvalidSchedule when the
+// start or end is missing or unreadable.
+func scanEvent(scan func(dest ...any) error) (*Event, error) {
+	var e Event
+	var startsAt, endsAt, updatedAt sql.NullString
+	var capacity sql.NullInt64
+	var rule, exdates sql.NullString
+	if err := scan(&e.Id, &e.OwnerId, &e.Name, &e.Description, &startsAt, &endsAt, &e.Timezone, &e.Location, &e.Visibility, &capacity, &rule, &exdates,
+		&e.Sequence, &updatedAt, &e.Version); err != nil {
 		return nil, err
 	}
+	e.UpdatedAt, _ = parseStoredTime(updatedAt)
+	if capacity.Valid {
+		c := int(capacity.Int64)
+		e.Capacity = &c
+	}
+	if rule.Valid {
+		e.RRule = &rule.String
+	}
+	if exdates.Valid {
+		e.ExDates = parseExdates(exdates.String)
+	}
 
-	return events, nil
+	var err error
+	if e.StartsAt, err = parseStoredTime(startsAt); err != nil {
+		return nil, fmt.Errorf("event %d: %w", e.Id, ErrInvalidSchedule)
+	}
+	if e.EndsAt, err = parseStoredTime(endsAt); err != nil {
+		return nil, fmt.Errorf("event %d: %w", e.Id, ErrInvalidSchedule)
+	}
+	e.localize()
+	return &e, nil
 }
 
-// GetAllByOwner retrieves events filtered by owner ID.
-func (m *EventModel) GetAllByOwner(ownerId int) ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	query := "SELECT id, owner_id, name, description, date, location FROM events WHERE owner_id = $1"
+// parseStoredTime reads a DATETIME column scanned as a string. The driver
+// hands back times it wrote itself as RFC 3339, except for computed columns
+// such as MAX(), which keep the time.Time String format it stores; the
+// migrations wrote SQLite's own format.
+func parseStoredTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, errors.New("missing time")
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST"} {
+		if t, err := time.Parse(layout, s.String); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse(time.DateTime, s.String)
+}
 
-	rows, err := m.DB.QueryContext(ctx, query, ownerId)
+// queryEvents runs a query selecting eventColumns and collects the rows.
+func (m *EventModel) queryEvents(ctx context.Context, query string, args ...any) ([]*Event, error) {
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -143,21 +256,11 @@ func (m *EventModel) GetAllByOwner(ownerId int) ([]*Event, error) {
 	events := []*Event{}
 
 	for rows.Next() {
-		var event Event
-		var dateStr string
-
-		if err := rows.Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &dateStr, &event.Location); err != nil {
-			return nil, err
-		}
-
-		parsedDate, err := parseFlexibleDate(dateStr)
+		event, err := scanEvent(rows.Scan)
 		if err != nil {
-			fmt.Printf("Warning: Invalid date format for event %d: %s\n", event.Id, dateStr)
-			continue
+			return nil, err
 		}
-
-		event.Date = parsedDate
-		events = append(events, &event)
+		events = append(events, event)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -167,79 +270,370 @@ func (m *EventModel) GetAllByOwner(ownerId int) ([]*Event, error) {
 	return events, nil
 }
 
-// parseFlexibleDate tries to parse various date formats
-func parseFlexibleDate(dateStr string) (time.Time, error) {
-	formats := []string{
-		time.RFC3339,           // "2006-01-02T15:04:05Z07:00"
-		"2006-01-02T15:04:05Z", // "2025-05-20T00:00:00Z"
-		"2006-01-02 15:04:05",  // "2025-01-02 15:04:05"
-		"2006-01-02",           // "2025-01-02"
-		"02-01-2006",           // "01-09-2025"
-	}
+// GetAll retrieves all events from the database with better error handling
+func (m *EventModel) GetAll() ([]*Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t, nil
-		}
+	return m.queryEvents(ctx, "SELECT "+eventColumns("")+" FROM events")
+}
+
+// eventStartKey is the start as a "YYYY-MM-DD HH:MM:SS" UTC string, which
+// sorts chronologically in both formats starts_at is stored in.
+const eventStartKey = "substr(e.starts_at, 1, 19)"
+
+// eventListSpec is how event lists can be sorted and filtered. Queries using
+// it must alias the events table as e.
+var eventListSpec = listSpec{
+	sorts: map[string]string{
+		"id":        "e.id",
+		"date":      eventStartKey,
+		"starts_at": eventStartKey,
+		"name":      "e.name COLLATE NOCASE",
+	},
+	defaultSort: "id",
+	filters: map[string]listFilter{
+		"q":         containsFilter("e.name", "e.description", "e.location"),
+		"location":  containsFilter("e.location"),
+		"date_from": dateFilter(eventStartKey, ">=", false),
+		"date_to":   dateFilter(eventStartKey, "<=", true),
+	},
+	idColumn: "e.id",
+}
+
+// viewableBy is the condition for events the user given by the placeholder
+// userArg may see in a list of someone else's events: public ones, and the
+// ones the user organizes or has an RSVP for, like the view rule of the
+// API's canOnEvent. Unlisted events are left out unless the user is
+// involved, since only people with the link should find them.
+func viewableBy(userArg string) string {
+	return `(e.visibility = 'public'
+	OR EXISTS (SELECT 1 FROM event_organizers vo WHERE vo.event_id = e.id AND vo.user_id = ` + userArg + `)
+	OR EXISTS (SELECT 1 FROM attendees va WHERE va.event_id = e.id AND va.user_id = ` + userArg + ` AND va.status IN ('going', 'waitlisted')))`
+}
+
+// EventFilters are the filter names event lists accept.
+var EventFilters = sortedKeys(eventListSpec.filters)
+
+// scanListedEvent adapts scanEvent to runList. Events with a broken
+// schedule are logged and left out, so one bad row does not fail the list;
+// InvalidSchedules reports them to administrators.
+func scanListedEvent(scan func(dest ...any) error) (*Event, bool, error) {
+	event, err := scanEvent(scan)
+	if errors.Is(err, ErrInvalidSchedule) {
+		log.Printf("skipping listed event: %v", err)
+		return nil, false, nil
 	}
+	return event, err == nil, err
+}
 
-	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+// GetAllByOrganizer retrieves the events userId helps organize in any role,
+// including the ones they own.
+func (m *EventModel) GetAllByOrganizer(userId int, params ListParams) (*Page[*Event], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return runList(ctx, m.DB, eventListSpec, listQuery{
+		columns: eventColumns("e"),
+		from:    "events e JOIN event_organizers o ON o.event_id = e.id",
+		where:   []string{"o.user_id = $1"},
+		args:    []any{userId},
+	}, params, scanListedEvent)
 }
 
-// Get retrieves a single event by its ID with better date handling
-func (m *EventModel) Get(id int) (*Event, error) {
+// Discover lists public events. Unless params say otherwise the soonest
+// events come first.
+func (m *EventModel) Discover(params ListParams) (*Page[*Event], error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := "SELECT id, owner_id, name, description, date, location FROM events WHERE id = $1"
+	spec := eventListSpec
+	spec.defaultSort = "starts_at"
+
+	return runList(ctx, m.DB, spec, listQuery{
+		columns: eventColumns("e"),
+		from:    "events e",
+		where:   []string{"e.visibility = 'public'"},
+	}, params, scanListedEvent)
+}
+
+// InvalidSchedule is an event whose stored start or end cannot be read,
+// typically a date left over from before events had a start and end time.
+// StartsAt and EndsAt are the stored values.
+type InvalidSchedule struct {
+	EventId  int     `json:"event_id"`
+	OwnerId  int     `json:"owner_id"`
+	Name     string  `json:"name"`
+	StartsAt *string `json:"starts_at"`
+	EndsAt   *string `json:"ends_at"`
+}
 
-	var event Event
-	var dateStr string
+// InvalidSchedules lists the events scanEvent refuses, which lists and
+// search leave out and which cannot be opened, so they can be repaired
+// with SetSchedule or deleted.
+func (m *EventModel) InvalidSchedules() ([]*InvalidSchedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &dateStr, &event.Location)
+	rows, err := m.DB.QueryContext(ctx, "SELECT id, owner_id, name, starts_at, ends_at FROM events ORDER BY id")
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+	defer rows.Close()
+
+	invalid := []*InvalidSchedule{}
+	for rows.Next() {
+		var e InvalidSchedule
+		var startsAt, endsAt sql.NullString
+		if err := rows.Scan(&e.EventId, &e.OwnerId, &e.Name, &startsAt, &endsAt); err != nil {
+			return nil, err
+		}
+		_, startErr := parseStoredTime(startsAt)
+		_, endErr := parseStoredTime(endsAt)
+		if startErr == nil && endErr == nil {
+			continue
+		}
+		if startsAt.Valid {
+			e.StartsAt = &startsAt.String
+		}
+		if endsAt.Valid {
+			e.EndsAt = &endsAt.String
+		}
+		invalid = append(invalid, &e)
+	}
+	return invalid, rows.Err()
+}
 
-	parsedDate, err := parseFlexibleDate(dateStr)
+// SetSchedule overwrites the start and end of the event id without reading
+// the event first, which is the way to repair one with an invalid
+// schedule. It returns sql.ErrNoRows when there is no such event.
+func (m *EventModel) SetSchedule(ctx context.Context, id int, startsAt, endsAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `UPDATE events SET starts_at = $1, ends_at = $2, sequence = sequence + 1, updated_at = $3, version = version + 1
+	WHERE id = $4`
+	result, err := m.DB.ExecContext(ctx, query, startsAt.UTC(), endsAt.UTC(), time.Now().UTC(), id)
 	if err != nil {
-		event.Date = time.Now()
-	} else {
-		event.Date = parsedDate
+		return err
 	}
-
-	return &event, nil
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-func (m *EventModel) Update(event *Event) error {
+// Get retrieves a single event by its ID
+func (m *EventModel) Get(id int) (*Event, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := "UPDATE events SET owner_id = $1, name = $2, description = $3, date = $4, location = $5 WHERE id = $6"
+	query := "SELECT " + eventColumns("") + " FROM events WHERE id = $1"
+
+	event, err := scanEvent(m.DB.QueryRowContext(ctx, query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return event, err
+}
+
+// Update saves event. Unless ifVersion is 0, it only does so while the
+// stored event is still at that version and returns ErrVersionConflict
+// otherwise, or sql.ErrNoRows when the event is gone.
+func (m *EventModel) Update(event *Event, ifVersion int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, event.OwnerId, event.Name, event.Description, event.Date, event.Location, event.Id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	if err := checkVersion(ctx, tx, event.Id, ifVersion); err != nil {
+		return err
+	}
+	if err := updateEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkVersion returns ErrVersionConflict unless the event id is at
+// ifVersion, or sql.ErrNoRows when it does not exist. An ifVersion of 0
+// matches any version.
+func checkVersion(ctx context.Context, q execQueryer, id, ifVersion int) error {
+	if ifVersion == 0 {
+		return nil
+	}
+	var version int
+	if err := q.QueryRowContext(ctx, "SELECT version FROM events WHERE id = $1", id).Scan(&version); err != nil {
+		return err
+	}
+	if version != ifVersion {
+		return ErrVersionConflict
+	}
 	return nil
 }
 
+// updateEvent is Update inside the caller's transaction.
+func updateEvent(ctx context.Context, q execQueryer, event *Event) error {
+	if event.capacityValue() == nil {
+		event.Capacity = nil
+	}
+
+	event.localize()
+	event.UpdatedAt = time.Now().UTC()
+
+	// The owner only changes through TransferOwnership
+	query := `UPDATE events SET name = $1, description = $2, starts_at = $3, ends_at = $4, timezone = $5, location = $6,
+	visibility = $7, capacity = $8, rrule = $9, exdates = $10, sequence = sequence + 1, updated_at = $11, version = version + 1
+	WHERE id = $12 RETURNING owner_id, sequence, version`
+
+	return q.QueryRowContext(ctx, query, event.Name, event.Description, event.StartsAt, event.EndsAt, event.Timezone, event.Location,
+		event.Visibility, event.capacityValue(), event.rruleValue(), event.exdatesValue(), event.UpdatedAt, event.Id).Scan(&event.OwnerId, &event.Sequence, &event.Version)
+}
+
+// touchEvent records a change to the event that updateEvent does not make,
+// such as to a single occurrence, so calendar clients pick it up.
+func touchEvent(ctx context.Context, q execQueryer, event *Event) error {
+	event.UpdatedAt = time.Now().UTC()
+	return q.QueryRowContext(ctx, "UPDATE events SET sequence = sequence + 1, updated_at = $1, version = version + 1 WHERE id = $2 RETURNING sequence, version",
+		event.UpdatedAt, event.Id).Scan(&event.Sequence, &event.Version)
+}
+
 func (m *EventModel) Delete(id int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := "DELETE FROM events WHERE id = $1"
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err := m.DB.ExecContext(ctx, query, id)
+	if err := deleteEvent(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteEvent is Delete inside the caller's transaction. It returns
+// sql.ErrNoRows when there is no such event.
+func deleteEvent(ctx context.Context, q execQueryer, id int) error {
+	// People who had the event in their calendar feed see it cancelled
+	if err := recordCancellations(ctx, q, "e.id = $1", id); err != nil {
+		return err
+	}
+
+	// Foreign keys are not enforced, so remove dependent rows by hand
+	if err := deleteAnswers(ctx, q, "event_id = $1", id); err != nil {
+		return err
+	}
+	if err := deleteCheckIns(ctx, q, "event_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM registration_fields WHERE event_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM attendees WHERE event_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM event_organizers WHERE event_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM invitations WHERE event_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM event_occurrences WHERE event_id = $1", id); err != nil {
+		return err
+	}
+
+	result, err := q.ExecContext(ctx, "DELETE FROM events WHERE id = $1", id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil
-		}
 		return err
 	}
-	return nil
+	return requireAffected(result)
+}
+
+// Batch operations.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// batchTimeout bounds a whole batch of event operations.
+var batchTimeout = 30 * time.Second
+
+// BatchOp is one operation of an event batch: creating Event, replacing the
+// event with Event's id by it, or deleting the event Id. Updates and
+// deletes with an IfVersion only apply to that version of the event, as
+// for Update. Batch sets Err when the operation fails.
+type BatchOp struct {
+	Op        string
+	Id        int
+	Event     *Event
+	IfVersion int
+	Err       error
+}
+
+// Batch runs ops in order in one transaction. When atomic is set, the
+// first failure rolls everything back and is returned. Otherwise each
+// operation runs in its own savepoint: a failed one is undone on its own,
+// with its Err set, and the others are committed. Updating or deleting an
+// event that does not exist fails with sql.ErrNoRows, and one that is no
+// longer at IfVersion with ErrVersionConflict.
+func (m *EventModel) Batch(ctx context.Context, ops []*BatchOp, atomic bool) error {
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, op := range ops {
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_op"); err != nil {
+				return err
+			}
+		}
+
+		switch op.Op {
+		case BatchCreate:
+			op.Err = insertEvent(ctx, tx, op.Event)
+		case BatchUpdate:
+			if op.Err = checkVersion(ctx, tx, op.Event.Id, op.IfVersion); op.Err == nil {
+				op.Err = updateEvent(ctx, tx, op.Event)
+			}
+		case BatchDelete:
+			if op.Err = checkVersion(ctx, tx, op.Id, op.IfVersion); op.Err == nil {
+				op.Err = deleteEvent(ctx, tx, op.Id)
+			}
+		default:
+			op.Err = fmt.Errorf("unknown batch operation %q", op.Op)
+		}
+
+		if atomic {
+			if op.Err != nil {
+				return op.Err
+			}
+			continue
+		}
+		if op.Err != nil {
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO batch_op"); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE batch_op"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
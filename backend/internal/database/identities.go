@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserIdentityModel stores the links between local accounts and accounts at
+// external OpenID Connect providers.
+type UserIdentityModel struct {
+	DB *sql.DB
+}
+
+// UserIdentity identifies an external account by its provider and the
+// provider's stable subject identifier. Email is what the provider reported
+// when the link was made and is informational only.
+type UserIdentity struct {
+	Id        int       `json:"id"`
+	UserId    int       `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func insertUserIdentity(ctx context.Context, q execQueryer, identity *UserIdentity) error {
+	identity.CreatedAt = time.Now().UTC()
+	query := `INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	return q.QueryRowContext(ctx, query, identity.UserId, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt).Scan(&identity.Id)
+}
+
+// Insert links an existing user to an external account.
+func (m *UserIdentityModel) Insert(identity *UserIdentity) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return insertUserIdentity(ctx, m.DB, identity)
+}
+
+// Get returns the identity for provider and subject, or nil if the external
+// account has never signed in here.
+func (m *UserIdentityModel) Get(provider, subject string) (*UserIdentity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at
+	FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	var identity UserIdentity
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(&identity.Id, &identity.UserId, &identity.Provider,
+		&identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateUser creates a new account for a first-time external sign-in and links
+// the identity to it in one transaction. emailVerified marks the address as
+// verified when the provider vouched for it.
+func (m *UserIdentityModel) CreateUser(ctx context.Context, user *User, identity *UserIdentity, emailVerified bool) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var verifiedAt *time.Time
+	if emailVerified {
+		now := time.Now().UTC()
+		verifiedAt = &now
+	}
+
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+	query := "INSERT INTO users (email, password, name, role, email_verified_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	if err := tx.QueryRowContext(ctx, query, user.Email, user.Password, user.Name, user.Role, verifiedAt).Scan(&user.Id); err != nil {
+		return err
+	}
+	user.EmailVerifiedAt = verifiedAt
+
+	identity.UserId = user.Id
+	if err := insertUserIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvitationNotFound is returned for unknown invitation tokens.
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvitationUnavailable is returned for invitations that expired, were
+	// used up, declined or revoked.
+	ErrInvitationUnavailable = errors.New("invitation is no longer valid")
+	// ErrInvitationForOther is returned when someone accepts an email
+	// invitation sent to another address.
+	ErrInvitationForOther = errors.New("invitation is for another email address")
+)
+
+type InvitationModel struct {
+	DB *sql.DB
+}
+
+// Invitation lets people join an event they could not see otherwise. Email
+// invitations are sent to one address and work once; shareable links have no
+// email and work MaxUses times (unlimited when nil). Only the SHA-256 hash of
+// the token is stored.
+type Invitation struct {
+	Id         int        `json:"id"`
+	EventId    int        `json:"eventId"`
+	CreatedBy  int        `json:"createdBy"`
+	Email      *string    `json:"email,omitempty"`
+	TokenHash  string     `json:"-"`
+	MaxUses    *int       `json:"max_uses"`
+	Uses       int        `json:"uses"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	DeclinedAt *time.Time `json:"declined_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the invitation can still be accepted at now.
+func (i *Invitation) Usable(now time.Time) bool {
+	switch {
+	case i.RevokedAt != nil, i.DeclinedAt != nil:
+		return false
+	case i.ExpiresAt != nil && !now.Before(*i.ExpiresAt):
+		return false
+	case i.MaxUses != nil && i.Uses >= *i.MaxUses:
+		return false
+	}
+	return true
+}
+
+const invitationColumns = "id, event_id, created_by, email, token_hash, max_uses, uses, expires_at, declined_at, revoked_at, created_at"
+
+func scanInvitation(scan func(dest ...any) error) (*Invitation, error) {
+	var inv Invitation
+	var email sql.NullString
+	var maxUses sql.NullInt64
+	var expiresAt, declinedAt, revokedAt sql.NullTime
+	err := scan(&inv.Id, &inv.EventId, &inv.CreatedBy, &email, &inv.TokenHash, &maxUses, &inv.Uses,
+		&expiresAt, &declinedAt, &revokedAt, &inv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if email.Valid {
+		inv.Email = &email.String
+	}
+	if maxUses.Valid {
+		n := int(maxUses.Int64)
+		inv.MaxUses = &n
+	}
+	if expiresAt.Valid {
+		inv.ExpiresAt = &expiresAt.Time
+	}
+	if declinedAt.Valid {
+		inv.DeclinedAt = &declinedAt.Time
+	}
+	if revokedAt.Valid {
+		inv.RevokedAt = &revokedAt.Time
+	}
+	return &inv, nil
+}
+
+func (m *InvitationModel) Insert(inv *Invitation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	inv.CreatedAt = time.Now().UTC()
+	if inv.ExpiresAt != nil {
+		utc := inv.ExpiresAt.UTC()
+		inv.ExpiresAt = &utc
+	}
+
+	query := `INSERT INTO invitations (event_id, created_by, email, token_hash, max_uses, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	return m.DB.QueryRowContext(ctx, query, inv.EventId, inv.CreatedBy, inv.Email, inv.TokenHash, inv.MaxUses, inv.ExpiresAt, inv.CreatedAt).Scan(&inv.Id)
+}
+
+func getInvitationByHash(ctx context.Context, q execQueryer, hash string) (*Invitation, error) {
+	query := "SELECT " + invitationColumns + " FROM invitations WHERE token_hash = $1"
+	inv, err := scanInvitation(q.QueryRowContext(ctx, query, hash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvitationNotFound
+	}
+	return inv, err
+}
+
+// GetByHash returns the invitation with the given token hash, or
+// ErrInvitationNotFound.
+func (m *InvitationModel) GetByHash(hash string) (*Invitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return getInvitationByHash(ctx, m.DB, hash)
+}
+
+// GetByEvent lists the event's invitations, newest first.
+func (m *InvitationModel) GetByEvent(eventId int) ([]*Invitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := "SELECT " + invitationColumns + " FROM invitations WHERE event_id = $1 ORDER BY id DESC"
+	rows, err := m.DB.QueryContext(ctx, query, eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// Revoke stops an invitation of the event from working. It returns
+// sql.ErrNoRows when the event has no such active invitation.
+func (m *InvitationModel) Revoke(ctx context.Context, eventId, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, "UPDATE invitations SET revoked_at = $1 WHERE id = $2 AND event_id = $3 AND revoked_at IS NULL",
+		time.Now().UTC(), id, eventId)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Accept redeems the invitation for user and RSVPs them as going, or puts
+// them on the waitlist when the event is full. Users who already have a spot
+// or a place on the waitlist keep it without using up the invitation. Email
+// invitations only work for the account with that address.
+func (m *InvitationModel) Accept(ctx context.Context, hash string, user *User) (*Invitation, *Attendee, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	inv, err := getInvitationByHash(ctx, tx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.Email != nil && !strings.EqualFold(*inv.Email, user.Email) {
+		return nil, nil, ErrInvitationForOther
+	}
+
+	existing, err := getAttendee(ctx, tx, inv.EventId, "", user.Id)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if existing != nil && (existing.Status == AttendeeGoing || existing.Status == AttendeeWaitlisted) {
+		return inv, existing, nil
+	}
+
+	if !inv.Usable(time.Now()) {
+		return nil, nil, ErrInvitationUnavailable
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE invitations SET uses = uses + 1 WHERE id = $1", inv.Id); err != nil {
+		return nil, nil, err
+	}
+	inv.Uses++
+
+	attendee, _, err := respond(ctx, tx, inv.EventId, "", user.Id, AttendeeGoing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return inv, attendee, nil
+}
+
+// Decline turns the invitation down. Email invitations stop working. When
+// user is signed in (Id not 0) their RSVP is recorded as declined too, which
+// may free a spot; the people promoted from the waitlist are returned. Like
+// Accept, signed-in users can only decline email invitations sent to their
+// own address.
+func (m *InvitationModel) Decline(ctx context.Context, hash string, user *User) (*Invitation, []*Attendee, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	inv, err := getInvitationByHash(ctx, tx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Id != 0 && inv.Email != nil && !strings.EqualFold(*inv.Email, user.Email) {
+		return nil, nil, ErrInvitationForOther
+	}
+	if inv.DeclinedAt != nil {
+		return inv, nil, nil
+	}
+	if !inv.Usable(time.Now()) {
+		return nil, nil, ErrInvitationUnavailable
+	}
+
+	if inv.Email != nil {
+		now := time.Now().UTC()
+		if _, err := tx.ExecContext(ctx, "UPDATE invitations SET declined_at = $1 WHERE id = $2", now, inv.Id); err != nil {
+			return nil, nil, err
+		}
+		inv.DeclinedAt = &now
+	}
+
+	var promoted []*Attendee
+	if user.Id != 0 {
+		if _, promoted, err = respond(ctx, tx, inv.EventId, "", user.Id, AttendeeDeclined); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return inv, promoted, nil
+}
@@ -0,0 +1,372 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ListParams asks for one page of a list. The zero value asks for the whole
+// list in its default order, which is what clients that predate pagination
+// get.
+type ListParams struct {
+	Limit   int               // 0 means no limit
+	Cursor  string            // NextCursor of the previous page
+	Sort    string            // a sort name of the list, "-" prefixed for descending
+	Filters map[string]string // filter name to value; names are checked against the list
+}
+
+// Page is one page of a list. NextCursor is nil on the last page; Total
+// counts all items matching the filters. It is an upper bound rather than an
+// exact count when the list leaves out rows it cannot read, such as events
+// with a broken schedule (see scanListedEvent).
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	Total      int     `json:"total"`
+}
+
+// ListParamError reports a sort, filter or cursor the list does not accept.
+// Handlers answer it with 400.
+type ListParamError struct {
+	Param string
+	Msg   string
+}
+
+func (e *ListParamError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Msg)
+}
+
+// listFilter turns a filter value into an SQL condition. Values are passed
+// as placeholders obtained from arg.
+type listFilter func(value string, arg func(any) string) (string, error)
+
+// listSpec is what a list may be sorted and filtered by. Sort expressions
+// must evaluate to text or integers so they survive the round trip through
+// the cursor; idColumn breaks ties and must be unique within the list.
+type listSpec struct {
+	sorts       map[string]string
+	defaultSort string
+	filters     map[string]listFilter
+	idColumn    string
+}
+
+// listQuery is the fixed part of a list query: the selected columns, the
+// FROM clause with any joins, and conditions that always apply.
+type listQuery struct {
+	columns string
+	from    string
+	where   []string
+	args    []any
+}
+
+// listCursor is the decoded form of Page.NextCursor: the sort it belongs to
+// and the sort key and id of the last item returned.
+type listCursor struct {
+	Sort string `json:"s"`
+	Key  any    `json:"k"`
+	Id   int64  `json:"i"`
+}
+
+func encodeCursor(cur listCursor) (string, error) {
+	data, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var cur listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&cur); err != nil {
+		return cur, err
+	}
+	// Numbers come back as json.Number; SQLite must see them as numbers
+	if n, ok := cur.Key.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			cur.Key = i
+		} else if f, err := n.Float64(); err == nil {
+			cur.Key = f
+		}
+	}
+	return cur, nil
+}
+
+// builtList is a list query with its sort resolved and its filters turned
+// into conditions.
+type builtList struct {
+	sortName string
+	sortExpr string
+	desc     bool
+	where    []string
+	args     []any
+}
+
+// buildList checks p's sort and filters against spec and adds the filters
+// to q's conditions.
+func buildList(spec listSpec, q listQuery, p ListParams) (*builtList, error) {
+	b := &builtList{sortName: p.Sort}
+	if b.sortName == "" {
+		b.sortName = spec.defaultSort
+	}
+	b.desc = strings.HasPrefix(b.sortName, "-")
+	sortExpr, ok := spec.sorts[strings.TrimPrefix(b.sortName, "-")]
+	if !ok {
+		return nil, &ListParamError{Param: "sort", Msg: "must be one of " + strings.Join(sortedKeys(spec.sorts), ", ") + " (prefix with - for descending)"}
+	}
+	b.sortExpr = sortExpr
+
+	b.where = append([]string{}, q.where...)
+	b.args = append([]any{}, q.args...)
+
+	names := make([]string, 0, len(p.Filters))
+	for name := range p.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		filter, ok := spec.filters[name]
+		if !ok {
+			return nil, &ListParamError{Param: "filter", Msg: name + " is not supported here"}
+		}
+		cond, err := filter(p.Filters[name], b.arg)
+		if err != nil {
+			return nil, &ListParamError{Param: name, Msg: err.Error()}
+		}
+		b.where = append(b.where, cond)
+	}
+	return b, nil
+}
+
+// arg adds v to the query's arguments and returns its placeholder.
+func (b *builtList) arg(v any) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *builtList) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+// direction returns the comparison that finds later rows and the ORDER BY
+// direction.
+func (b *builtList) direction() (cmp, dir string) {
+	if b.desc {
+		return "<", "DESC"
+	}
+	return ">", "ASC"
+}
+
+// after limits the query to the rows that come after cur in its order.
+func (b *builtList) after(spec listSpec, cur listCursor) {
+	cmp, _ := b.direction()
+	k, id := b.arg(cur.Key), b.arg(cur.Id)
+	b.where = append(b.where, fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND %[4]s %[2]s %[5]s))", b.sortExpr, cmp, k, spec.idColumn, id))
+}
+
+// query is the SELECT of q's columns followed by the sort key and id, in
+// the list's order.
+func (b *builtList) query(spec listSpec, q listQuery) string {
+	_, dir := b.direction()
+	return fmt.Sprintf("SELECT %s, %s, %s FROM %s%s ORDER BY %s %s, %s %s",
+		q.columns, b.sortExpr, spec.idColumn, q.from, b.whereClause(), b.sortExpr, dir, spec.idColumn, dir)
+}
+
+// runList runs a keyset-paginated query described by spec and q. scan reads
+// one row of q.columns; it may drop a row by returning keep=false. Dropped
+// rows still count towards Page.Total, which is counted in SQL.
+func runList[T any](ctx context.Context, db *sql.DB, spec listSpec, q listQuery, p ListParams,
+	scan func(scan func(dest ...any) error) (item T, keep bool, err error)) (*Page[T], error) {
+	b, err := buildList(spec, q, p)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: []T{}}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+q.from+b.whereClause(), b.args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if p.Cursor != "" {
+		cur, err := decodeCursor(p.Cursor)
+		if err != nil || cur.Sort != b.sortName {
+			return nil, &ListParamError{Param: "cursor", Msg: "not a cursor for this list and sort"}
+		}
+		b.after(spec, cur)
+	}
+
+	query := b.query(spec, q)
+	if p.Limit > 0 {
+		// One extra row tells whether there is another page
+		query += " LIMIT " + b.arg(p.Limit+1)
+	}
+
+	rows, err := db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var last listCursor
+	n := 0
+	for rows.Next() {
+		n++
+		if p.Limit > 0 && n > p.Limit {
+			next, err := encodeCursor(last)
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor = &next
+			break
+		}
+
+		var key any
+		last = listCursor{Sort: b.sortName}
+		item, keep, err := scan(func(dest ...any) error {
+			return rows.Scan(append(dest, &key, &last.Id)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		last.Key = key
+		if keep {
+			page.Items = append(page.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// streamPageSize is how many rows streamList reads per query.
+const streamPageSize = 500
+
+// streamList hands every row matching spec and q to fn, in the requested
+// order but without counting, so long lists are never held in memory.
+// Exports use it. The rows are read in keyset pages like runList's, and
+// each page's query has finished before fn sees its rows: a slow reader
+// must not keep SQLite's read lock, which would block every writer. Rows
+// changed while the list is read may or may not be included. Errors about
+// p come back before fn is first called.
+func streamList[T any](ctx context.Context, db *sql.DB, spec listSpec, q listQuery, p ListParams,
+	scan func(scan func(dest ...any) error) (T, error), fn func(T) error) error {
+	base, err := buildList(spec, q, p)
+	if err != nil {
+		return err
+	}
+
+	var cur *listCursor
+	for {
+		b := *base
+		b.where = append([]string{}, base.where...)
+		b.args = append([]any{}, base.args...)
+		if cur != nil {
+			b.after(spec, *cur)
+		}
+		query := b.query(spec, q) + " LIMIT " + b.arg(streamPageSize)
+
+		items, last, err := streamPage(ctx, db, query, b, scan)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(items) < streamPageSize {
+			return nil
+		}
+		cur = &last
+	}
+}
+
+// streamPage reads one page of streamList and the cursor of its last row.
+func streamPage[T any](ctx context.Context, db *sql.DB, query string, b builtList,
+	scan func(scan func(dest ...any) error) (T, error)) ([]T, listCursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	last := listCursor{Sort: b.sortName}
+	rows, err := db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, last, err
+	}
+	defer rows.Close()
+
+	items := make([]T, 0, streamPageSize)
+	for rows.Next() {
+		var key any
+		item, err := scan(func(dest ...any) error {
+			return rows.Scan(append(dest, &key, &last.Id)...)
+		})
+		if err != nil {
+			return nil, last, err
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		last.Key = key
+		items = append(items, item)
+	}
+	return items, last, rows.Err()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsFilter matches column case-insensitively against a substring.
+func containsFilter(columns ...string) listFilter {
+	return func(value string, arg func(any) string) (string, error) {
+		p := arg("%" + escapeLike(value) + "%")
+		conds := make([]string, len(columns))
+		for i, col := range columns {
+			conds[i] = fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, col, p)
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", nil
+	}
+}
+
+// dateFilter compares the text key expr (see eventStartKey) against a date
+// given as RFC3339 or YYYY-MM-DD. With upper set, a bare date covers the
+// whole day.
+func dateFilter(expr, op string, upper bool) listFilter {
+	return func(value string, arg func(any) string) (string, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+			if err != nil {
+				return "", fmt.Errorf("use RFC3339 or YYYY-MM-DD")
+			}
+			if upper {
+				t = t.Add(24*time.Hour - time.Second)
+			}
+		}
+		return expr + " " + op + " " + arg(t.UTC().Format("2006-01-02 15:04:05")), nil
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"rest-api-in-gin/internal/loginlimit"
+	"time"
+)
+
+// LoginAttemptModel is the SQLite-backed loginlimit.Store. Unlike the memory
+// store its state survives restarts, so restarting the server does not hand
+// an attacker a fresh set of guesses.
+type LoginAttemptModel struct {
+	DB *sql.DB
+}
+
+var _ loginlimit.Store = (*LoginAttemptModel)(nil)
+
+func (m *LoginAttemptModel) Get(ctx context.Context, key string) (loginlimit.State, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var failures int
+	var lastFailure, lockedUntil int64
+	query := "SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1"
+	err := m.DB.QueryRowContext(ctx, query, key).Scan(&failures, &lastFailure, &lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return loginlimit.State{}, nil
+		}
+		return loginlimit.State{}, err
+	}
+
+	return loginlimit.State{
+		Failures:      failures,
+		LastFailureAt: time.Unix(lastFailure, 0),
+		LockedUntil:   time.Unix(lockedUntil, 0),
+	}, nil
+}
+
+func (m *LoginAttemptModel) Increment(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	// A single upsert keeps the increment atomic under concurrent logins
+	query := `
+	INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+	ON CONFLICT(key) DO UPDATE SET
+		failures = CASE WHEN $2 - login_attempts.last_failure_at > $3 THEN 1 ELSE login_attempts.failures + 1 END,
+		last_failure_at = $2
+	RETURNING failures`
+
+	var failures int
+	err := m.DB.QueryRowContext(ctx, query, key, now.Unix(), int64(window.Seconds())).Scan(&failures)
+	return failures, err
+}
+
+func (m *LoginAttemptModel) Lock(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE login_attempts SET locked_until = MAX(locked_until, $1) WHERE key = $2"
+	_, err := m.DB.ExecContext(ctx, query, until.Unix(), key)
+	return err
+}
+
+func (m *LoginAttemptModel) Reset(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
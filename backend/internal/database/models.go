@@ -3,15 +3,41 @@ package database
 import "database/sql"
 
 type Models struct {
-	Users     UserModel
-	Events    EventModel
-	Attendees AttendeeModel
+	Users          UserModel
+	Events         EventModel
+	Attendees      AttendeeModel
+	RefreshTokens  RefreshTokenModel
+	Sessions       SessionModel
+	PasswordResets PasswordResetModel
+	LoginAttempts  LoginAttemptModel
+	Audit          AuditModel
+	RecoveryCodes  RecoveryCodeModel
+	Identities     UserIdentityModel
+	APIKeys        APIKeyModel
+	Organizers     OrganizerModel
+	Invitations    InvitationModel
+	Occurrences    OccurrenceModel
+	Calendars      CalendarModel
+	Registration   RegistrationModel
 }
 
-func NewModels(db *sql.DB) Models{
+func NewModels(db *sql.DB) Models {
 	return Models{
-		Users:     UserModel{DB: db},
-		Events:    EventModel{DB: db},
-		Attendees: AttendeeModel{DB: db},
+		Users:          UserModel{DB: db},
+		Events:         EventModel{DB: db},
+		Attendees:      AttendeeModel{DB: db},
+		RefreshTokens:  RefreshTokenModel{DB: db},
+		Sessions:       SessionModel{DB: db},
+		PasswordResets: PasswordResetModel{DB: db},
+		LoginAttempts:  LoginAttemptModel{DB: db},
+		Audit:          AuditModel{DB: db},
+		RecoveryCodes:  RecoveryCodeModel{DB: db},
+		Identities:     UserIdentityModel{DB: db},
+		APIKeys:        APIKeyModel{DB: db},
+		Organizers:     OrganizerModel{DB: db},
+		Invitations:    InvitationModel{DB: db},
+		Occurrences:    OccurrenceModel{DB: db},
+		Calendars:      CalendarModel{DB: db},
+		Registration:   RegistrationModel{DB: db},
 	}
-}
\ No newline at end of file
+}
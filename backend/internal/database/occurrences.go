@@ -0,0 +1,579 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rest-api-in-gin/internal/rrule"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrEndBeforeStart is returned when changes would make an occurrence end
+// before it starts.
+var ErrEndBeforeStart = errors.New("ends_at must be after starts_at")
+
+type OccurrenceModel struct {
+	DB *sql.DB
+}
+
+// OccurrenceKey identifies an occurrence of a recurring event by its
+// original start. It is what URLs, RSVPs and EXDATEs use, and stays the same
+// when a single occurrence is moved.
+func OccurrenceKey(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Occurrence is one instance of an event, with any changes made to it alone
+// applied. One-off events have a single occurrence.
+type Occurrence struct {
+	EventId       int       `json:"eventId"`
+	Occurrence    string    `json:"occurrence"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	Timezone      string    `json:"timezone"`
+	StartsAtLocal time.Time `json:"starts_at_local"`
+	EndsAtLocal   time.Time `json:"ends_at_local"`
+	Location      string    `json:"location"`
+	Modified      bool      `json:"modified"`
+}
+
+// OccurrenceChanges are edits to one or more occurrences. Nil fields are
+// left alone; a new start without a new end keeps the length.
+type OccurrenceChanges struct {
+	Name        *string    `json:"name" binding:"omitempty,min=3"`
+	Description *string    `json:"description" binding:"omitempty,min=10"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	Location    *string    `json:"location" binding:"omitempty,min=3"`
+}
+
+// over returns ch with the fields set in later replacing its own.
+func (ch OccurrenceChanges) over(later OccurrenceChanges) OccurrenceChanges {
+	if later.Name != nil {
+		ch.Name = later.Name
+	}
+	if later.Description != nil {
+		ch.Description = later.Description
+	}
+	if later.StartsAt != nil {
+		ch.StartsAt = later.StartsAt
+	}
+	if later.EndsAt != nil {
+		ch.EndsAt = later.EndsAt
+	}
+	if later.Location != nil {
+		ch.Location = later.Location
+	}
+	return ch
+}
+
+// apply returns o with the changes made, and ErrEndBeforeStart when that
+// leaves it ending before it starts.
+func (ch *OccurrenceChanges) apply(o Occurrence, loc *time.Location) (*Occurrence, error) {
+	if ch.Name != nil {
+		o.Name = *ch.Name
+	}
+	if ch.Description != nil {
+		o.Description = *ch.Description
+	}
+	if ch.StartsAt != nil {
+		length := o.EndsAt.Sub(o.StartsAt)
+		o.StartsAt = ch.StartsAt.UTC()
+		o.EndsAt = o.StartsAt.Add(length)
+	}
+	if ch.EndsAt != nil {
+		o.EndsAt = ch.EndsAt.UTC()
+	}
+	if ch.Location != nil {
+		o.Location = *ch.Location
+	}
+	if !o.EndsAt.After(o.StartsAt) {
+		return nil, ErrEndBeforeStart
+	}
+	o.StartsAtLocal, o.EndsAtLocal = o.StartsAt.In(loc), o.EndsAt.In(loc)
+	return &o, nil
+}
+
+// applyToSeries makes the changes, given for the occurrence starting at
+// start, to the series itself: a new start moves every occurrence by the
+// same amount. It returns how far they moved.
+func (ch *OccurrenceChanges) applyToSeries(event *Event, start time.Time) (time.Duration, error) {
+	base := newOccurrence(event, start, nil)
+	o, err := ch.apply(*base, event.TimeLocation())
+	if err != nil {
+		return 0, err
+	}
+	delta := o.StartsAt.Sub(base.StartsAt)
+	length := o.EndsAt.Sub(o.StartsAt)
+
+	event.Name, event.Description, event.Location = o.Name, o.Description, o.Location
+	event.StartsAt = event.StartsAt.Add(delta)
+	event.EndsAt = event.StartsAt.Add(length)
+	event.ExDates = shiftDates(event.ExDates, delta)
+	return delta, nil
+}
+
+// overrides loads the changes made to single occurrences of the event, by
+// occurrence key.
+func overrides(ctx context.Context, q execQueryer, eventId int) (map[string]*OccurrenceChanges, error) {
+	rows, err := q.QueryContext(ctx, "SELECT occurrence, name, description, starts_at, ends_at, location FROM event_occurrences WHERE event_id = $1", eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := map[string]*OccurrenceChanges{}
+	for rows.Next() {
+		var key string
+		var name, description, location sql.NullString
+		var startsAt, endsAt sql.NullTime
+		if err := rows.Scan(&key, &name, &description, &startsAt, &endsAt, &location); err != nil {
+			return nil, err
+		}
+		var ch OccurrenceChanges
+		if name.Valid {
+			ch.Name = &name.String
+		}
+		if description.Valid {
+			ch.Description = &description.String
+		}
+		if startsAt.Valid {
+			ch.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			ch.EndsAt = &endsAt.Time
+		}
+		if location.Valid {
+			ch.Location = &location.String
+		}
+		changes[key] = &ch
+	}
+	return changes, rows.Err()
+}
+
+// newOccurrence builds the occurrence of event originally starting at start.
+func newOccurrence(event *Event, start time.Time, changes map[string]*OccurrenceChanges) *Occurrence {
+	loc := event.TimeLocation()
+	o := Occurrence{
+		EventId:     event.Id,
+		Occurrence:  OccurrenceKey(start),
+		Name:        event.Name,
+		Description: event.Description,
+		StartsAt:    start.UTC(),
+		EndsAt:      start.UTC().Add(event.EndsAt.Sub(event.StartsAt)),
+		Timezone:    event.Timezone,
+		Location:    event.Location,
+	}
+	o.StartsAtLocal, o.EndsAtLocal = o.StartsAt.In(loc), o.EndsAt.In(loc)
+	if ch, ok := changes[o.Occurrence]; ok {
+		// Stored changes were checked when they were made; should the
+		// series have changed since so that they no longer fit, the
+		// occurrence is shown unchanged
+		if changed, err := ch.apply(o, loc); err == nil {
+			changed.Modified = true
+			return changed
+		}
+	}
+	return &o
+}
+
+// seriesStart is the start of the event's first occurrence in its timezone,
+// which recurrence rules count from.
+func seriesStart(event *Event) time.Time {
+	return event.StartsAt.In(event.TimeLocation())
+}
+
+// List returns the occurrences of the event that start in [from, to),
+// soonest first, at most limit of them. Occurrences moved into or out of
+// the window are placed by their new start.
+func (m *OccurrenceModel) List(event *Event, from, to time.Time, limit int) ([]*Occurrence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rule, err := event.Rule()
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		occurrences := []*Occurrence{}
+		if !event.StartsAt.Before(from) && event.StartsAt.Before(to) {
+			occurrences = append(occurrences, newOccurrence(event, event.StartsAt, nil))
+		}
+		return occurrences, nil
+	}
+
+	changes, err := overrides(ctx, m.DB, event.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	starts := rule.Between(seriesStart(event), from, to, event.ExDates, 0)
+	for key, ch := range changes {
+		start, err := time.Parse(time.RFC3339, key)
+		if err != nil || ch.StartsAt == nil || ch.StartsAt.Before(from) || !ch.StartsAt.Before(to) {
+			continue
+		}
+		if (start.Before(from) || !start.Before(to)) && occurs(event, start) {
+			starts = append(starts, start)
+		}
+	}
+
+	occurrences := []*Occurrence{}
+	for _, start := range starts {
+		o := newOccurrence(event, start, changes)
+		if !o.StartsAt.Before(from) && o.StartsAt.Before(to) {
+			occurrences = append(occurrences, o)
+		}
+	}
+	slices.SortFunc(occurrences, func(a, b *Occurrence) int {
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+	if limit > 0 && len(occurrences) > limit {
+		occurrences = occurrences[:limit]
+	}
+	return occurrences, nil
+}
+
+// occurs reports whether the event's rule produces start and it was not
+// cancelled.
+func occurs(event *Event, start time.Time) bool {
+	rule, err := event.Rule()
+	if err != nil || rule == nil {
+		return err == nil && start.Equal(event.StartsAt)
+	}
+	for _, t := range event.ExDates {
+		if t.Equal(start) {
+			return false
+		}
+	}
+	return rule.Index(seriesStart(event), start) >= 0
+}
+
+// Get returns the occurrence of the event that originally starts at start,
+// or nil when there is no such occurrence or it was cancelled.
+func (m *OccurrenceModel) Get(event *Event, start time.Time) (*Occurrence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if !occurs(event, start) {
+		return nil, nil
+	}
+	changes, err := overrides(ctx, m.DB, event.Id)
+	if err != nil {
+		return nil, err
+	}
+	return newOccurrence(event, start, changes), nil
+}
+
+// Modified returns the occurrences of the event that were changed on
+// their own and still take place, by original start.
+func (m *OccurrenceModel) Modified(event *Event) ([]*Occurrence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	changes, err := overrides(ctx, m.DB, event.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := []*Occurrence{}
+	for key := range changes {
+		start, err := time.Parse(time.RFC3339, key)
+		if err != nil || !occurs(event, start) {
+			continue
+		}
+		occurrences = append(occurrences, newOccurrence(event, start, changes))
+	}
+	slices.SortFunc(occurrences, func(a, b *Occurrence) int {
+		return strings.Compare(a.Occurrence, b.Occurrence)
+	})
+	return occurrences, nil
+}
+
+// Modify changes a single occurrence of the event, on top of earlier
+// changes to it. It returns ErrEndBeforeStart when the occurrence would end
+// before it starts.
+func (m *OccurrenceModel) Modify(ctx context.Context, event *Event, start time.Time, changes OccurrenceChanges) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, err := overrides(ctx, tx, event.Id)
+	if err != nil {
+		return err
+	}
+	key := OccurrenceKey(start)
+	merged := changes
+	if earlier, ok := existing[key]; ok {
+		merged = earlier.over(changes)
+	}
+	if _, err := merged.apply(*newOccurrence(event, start, nil), event.TimeLocation()); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO event_occurrences (event_id, occurrence, name, description, starts_at, ends_at, location)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (event_id, occurrence) DO UPDATE SET
+		name = excluded.name,
+		description = excluded.description,
+		starts_at = excluded.starts_at,
+		ends_at = excluded.ends_at,
+		location = excluded.location`
+
+	if _, err := tx.ExecContext(ctx, query, event.Id, key, merged.Name, merged.Description,
+		utcOrNil(merged.StartsAt), utcOrNil(merged.EndsAt), merged.Location); err != nil {
+		return err
+	}
+	if err := touchEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// Cancel cancels a single occurrence: it is added to the event's EXDATEs,
+// its changes are dropped and its RSVPs are cancelled.
+func (m *OccurrenceModel) Cancel(ctx context.Context, event *Event, start time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	key := OccurrenceKey(start)
+	event.ExDates = append(event.ExDates, start.UTC())
+	if err := updateEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM event_occurrences WHERE event_id = $1 AND occurrence = $2", event.Id, key); err != nil {
+		return err
+	}
+	if err := cancelOccurrenceRSVPs(ctx, tx, event.Id, "occurrence = $2", key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// End cancels the occurrence starting at start and all later ones by ending
+// the series before it.
+func (m *OccurrenceModel) End(ctx context.Context, event *Event, start time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rule, err := event.Rule()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	key := OccurrenceKey(start)
+	endSeries(event, rule, start, rule.Index(seriesStart(event), start))
+
+	if err := updateEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM event_occurrences WHERE event_id = $1 AND occurrence >= $2", event.Id, key); err != nil {
+		return err
+	}
+	if err := cancelOccurrenceRSVPs(ctx, tx, event.Id, "occurrence >= $2", key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Split ends the series before the occurrence starting at start and
+// continues it as a new event with the changes made, which is returned.
+// The new event gets the same organizers and whole-series RSVPs; changes
+// and RSVPs of the moved occurrences go with them.
+func (m *OccurrenceModel) Split(ctx context.Context, event *Event, start time.Time, changes OccurrenceChanges) (*Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rule, err := event.Rule()
+	if err != nil {
+		return nil, err
+	}
+	index := rule.Index(seriesStart(event), start)
+	if index <= 0 {
+		return nil, fmt.Errorf("cannot split series at its occurrence %d", index)
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The new series starts with this occurrence as it was planned
+	next := *event
+	next.Id = 0
+	next.StartsAt = start.UTC()
+	next.EndsAt = next.StartsAt.Add(event.EndsAt.Sub(event.StartsAt))
+	_, next.ExDates = splitDates(event.ExDates, start)
+	delta, err := changes.applyToSeries(&next, start)
+	if err != nil {
+		return nil, err
+	}
+	nextRule := *rule
+	if rule.Count > 0 {
+		nextRule.Count = rule.Count - index
+	}
+	nextRuleText := nextRule.String()
+	next.RRule = &nextRuleText
+
+	if err := insertEvent(ctx, tx, &next); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO event_organizers (event_id, user_id, role, created_at)
+		SELECT $1, user_id, role, created_at FROM event_organizers WHERE event_id = $2 AND role != $3`,
+		next.Id, event.Id, OrganizerOwner); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO attendees (user_id, event_id, occurrence, status, updated_at)
+		SELECT user_id, $1, occurrence, status, updated_at FROM attendees WHERE event_id = $2 AND occurrence = ''`,
+		next.Id, event.Id); err != nil {
+		return nil, err
+	}
+	if err := moveOccurrences(ctx, tx, event.Id, next.Id, OccurrenceKey(start), delta); err != nil {
+		return nil, err
+	}
+
+	endSeries(event, rule, start, index)
+	if err := updateEvent(ctx, tx, event); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// UpdateSeries makes the changes to every occurrence. A new start for the
+// occurrence starting at start moves the whole series by the same amount,
+// along with its cancelled and changed occurrences and their RSVPs.
+func (m *OccurrenceModel) UpdateSeries(ctx context.Context, event *Event, start time.Time, changes OccurrenceChanges) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	delta, err := changes.applyToSeries(event, start)
+	if err != nil {
+		return err
+	}
+
+	if err := updateEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	if err := moveOccurrences(ctx, tx, event.Id, event.Id, "", delta); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// cancelOccurrenceRSVPs cancels the RSVPs of the event's occurrences
+// matching where, which gets the occurrence key as $2.
+func cancelOccurrenceRSVPs(ctx context.Context, q execQueryer, eventId int, where, key string) error {
+	query := "UPDATE attendees SET status = $3, updated_at = $4 WHERE event_id = $1 AND occurrence != '' AND " + where + " AND status != $3"
+	_, err := q.ExecContext(ctx, query, eventId, key, AttendeeCancelled, time.Now().UTC())
+	return err
+}
+
+// moveOccurrences moves the changes, RSVPs and check-ins of the
+// occurrences of event from that start at or after key to event to,
+// shifting them by delta.
+func moveOccurrences(ctx context.Context, q execQueryer, from, to int, key string, delta time.Duration) error {
+	if from == to && delta == 0 {
+		return nil
+	}
+	shift := fmt.Sprintf("%+d seconds", int64(delta/time.Second))
+
+	for _, table := range []string{"event_occurrences", "attendees"} {
+		// Park the new keys under a prefix first so rows moving onto each
+		// other's keys do not trip the unique index
+		query := fmt.Sprintf(`UPDATE %s SET event_id = $1, occurrence = 'moving:' || strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', occurrence, $2)
+			WHERE event_id = $3 AND occurrence != '' AND occurrence >= $4`, table)
+		if _, err := q.ExecContext(ctx, query, to, shift, from, key); err != nil {
+			return err
+		}
+		query = fmt.Sprintf(`UPDATE %s SET occurrence = substr(occurrence, 8) WHERE event_id = $1 AND occurrence LIKE 'moving:%%'`, table)
+		if _, err := q.ExecContext(ctx, query, to); err != nil {
+			return err
+		}
+	}
+	if delta == 0 {
+		return nil
+	}
+
+	// Check-ins at the moved occurrences follow them
+	query := `UPDATE check_ins SET occurrence = 'moving:' || strftime('%Y-%m-%dT%H:%M:%SZ', occurrence, $1)
+		WHERE occurrence != '' AND occurrence >= $2 AND attendee_id IN (SELECT id FROM attendees WHERE event_id = $3)`
+	if _, err := q.ExecContext(ctx, query, shift, key, to); err != nil {
+		return err
+	}
+	_, err := q.ExecContext(ctx, `UPDATE check_ins SET occurrence = substr(occurrence, 8) WHERE occurrence LIKE 'moving:%'`)
+	return err
+}
+
+// endSeries ends the event's series before its occurrence starting at
+// start, number index counting from 0. Series with a COUNT keep counting;
+// the others get an UNTIL.
+func endSeries(event *Event, rule *rrule.Rule, start time.Time, index int) {
+	if rule.Count > 0 {
+		rule.Count = index
+	} else {
+		rule.SetUntil(start.Add(-time.Second))
+	}
+	text := rule.String()
+	event.RRule = &text
+	event.ExDates, _ = splitDates(event.ExDates, start)
+}
+
+// splitDates splits dates into the ones before t and the rest.
+func splitDates(dates []time.Time, t time.Time) (before, after []time.Time) {
+	for _, d := range dates {
+		if d.Before(t) {
+			before = append(before, d)
+		} else {
+			after = append(after, d)
+		}
+	}
+	return before, after
+}
+
+func shiftDates(dates []time.Time, delta time.Duration) []time.Time {
+	shifted := make([]time.Time, len(dates))
+	for i, d := range dates {
+		shifted[i] = d.Add(delta)
+	}
+	return shifted
+}
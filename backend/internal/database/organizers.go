@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Organizer roles. The owner can do everything including managing the team,
+// editors can change the event and its attendees, and check-in staff can see
+// the attendee list to admit people.
+const (
+	OrganizerOwner   = "owner"
+	OrganizerEditor  = "editor"
+	OrganizerCheckIn = "check_in"
+)
+
+// ErrOrganizerExists is returned when adding someone who already organizes
+// the event.
+var ErrOrganizerExists = errors.New("user is already an organizer")
+
+type OrganizerModel struct {
+	DB *sql.DB
+}
+
+// Organizer is a member of an event's team, with the user's public details.
+type Organizer struct {
+	EventId   int       `json:"eventId"`
+	UserId    int       `json:"userId"`
+	Role      string    `json:"role"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func insertOrganizer(ctx context.Context, q execQueryer, eventId, userId int, role string) error {
+	query := "INSERT INTO event_organizers (event_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)"
+	_, err := q.ExecContext(ctx, query, eventId, userId, role, time.Now().UTC())
+	return err
+}
+
+// Role returns the user's role on the event, or "" if they are not an
+// organizer.
+func (m *OrganizerModel) Role(eventId, userId int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var role string
+	err := m.DB.QueryRowContext(ctx, "SELECT role FROM event_organizers WHERE event_id = $1 AND user_id = $2", eventId, userId).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetByEvent lists the event's organizers, owner first.
+func (m *OrganizerModel) GetByEvent(eventId int) ([]*Organizer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT o.event_id, o.user_id, o.role, u.name, u.email, o.created_at
+	FROM event_organizers o JOIN users u ON u.id = o.user_id
+	WHERE o.event_id = $1
+	ORDER BY CASE o.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, o.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizers := []*Organizer{}
+	for rows.Next() {
+		var o Organizer
+		if err := rows.Scan(&o.EventId, &o.UserId, &o.Role, &o.Name, &o.Email, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		organizers = append(organizers, &o)
+	}
+	return organizers, rows.Err()
+}
+
+// Add makes userId an organizer of the event with role, which must not be
+// OrganizerOwner; use TransferOwnership for that. ErrOrganizerExists is
+// returned when they already are one, also when two requests add them at
+// the same time.
+func (m *OrganizerModel) Add(ctx context.Context, eventId, userId int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `INSERT INTO event_organizers (event_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (event_id, user_id) DO NOTHING`
+	result, err := m.DB.ExecContext(ctx, query, eventId, userId, role, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrOrganizerExists
+	}
+	return nil
+}
+
+// Remove takes userId off the event's team. The owner cannot be removed;
+// sql.ErrNoRows is returned when there is no such non-owner organizer.
+func (m *OrganizerModel) Remove(ctx context.Context, eventId, userId int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM event_organizers WHERE event_id = $1 AND user_id = $2 AND role != 'owner'", eventId, userId)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// TransferOwnership makes newOwnerId the owner of the event. The previous
+// owner stays on the team as an editor. Like any change to the event it
+// moves its version on, so edits based on the old owner fail If-Match.
+func (m *OrganizerModel) TransferOwnership(ctx context.Context, event *Event, newOwnerId int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE event_organizers SET role = 'editor' WHERE event_id = $1 AND role = 'owner'", event.Id); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE event_organizers SET role = 'owner' WHERE event_id = $1 AND user_id = $2", event.Id, newOwnerId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		if err := insertOrganizer(ctx, tx, event.Id, newOwnerId, OrganizerOwner); err != nil {
+			return err
+		}
+	}
+
+	updatedAt := time.Now().UTC()
+	err = tx.QueryRowContext(ctx, `UPDATE events SET owner_id = $1, sequence = sequence + 1, updated_at = $2, version = version + 1
+	WHERE id = $3 RETURNING sequence, version`, newOwnerId, updatedAt, event.Id).Scan(&event.Sequence, &event.Version)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	event.OwnerId, event.UpdatedAt = newOwnerId, updatedAt
+	return nil
+}
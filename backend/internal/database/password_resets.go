@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrPasswordResetTokenInvalid covers unknown, expired and already used tokens.
+// Callers get a single error so responses don't reveal which case applied.
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	Id        int
+	UserId    int
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (m *PasswordResetModel) Insert(token *PasswordResetToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	token.CreatedAt = time.Now().UTC()
+	query := `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+	VALUES ($1, $2, $3, $4) RETURNING id`
+
+	return m.DB.QueryRowContext(ctx, query, token.UserId, token.TokenHash, token.ExpiresAt.UTC(), token.CreatedAt).Scan(&token.Id)
+}
+
+// Consume redeems the token identified by hash and sets the user's password
+// to passwordHash. In the same transaction it marks every outstanding reset
+// token of the user as used, revokes all sessions and refresh tokens and
+// deletes the user's API keys, so whoever knew the old password loses every
+// credential they could have made with it. It returns the user id.
+func (m *PasswordResetModel) Consume(ctx context.Context, hash string, passwordHash []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var token PasswordResetToken
+	var usedAt sql.NullTime
+	query := "SELECT id, user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = $1"
+	err = tx.QueryRowContext(ctx, query, hash).Scan(&token.Id, &token.UserId, &token.ExpiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrPasswordResetTokenInvalid
+		}
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	if usedAt.Valid || now.After(token.ExpiresAt) {
+		return 0, ErrPasswordResetTokenInvalid
+	}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"UPDATE users SET password = $1 WHERE id = $2", []any{passwordHash, token.UserId}},
+		{"UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL", []any{now, token.UserId}},
+		{"UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", []any{now, token.UserId}},
+		{"UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", []any{now, token.UserId}},
+		{"DELETE FROM api_keys WHERE user_id = $1", []any{token.UserId}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return token.UserId, nil
+}
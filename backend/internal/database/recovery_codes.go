@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RecoveryCodeModel manages the one-time codes that stand in for a TOTP code
+// when the user has lost their authenticator. Only hashes are stored.
+type RecoveryCodeModel struct {
+	DB *sql.DB
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId int, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)", userId, hash, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Use redeems an unused recovery code. It returns false when the code is
+// unknown or was already used.
+func (m *RecoveryCodeModel) Use(ctx context.Context, userId int, hash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+	result, err := m.DB.ExecContext(ctx, query, time.Now().UTC(), userId, hash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CountUnused returns how many recovery codes the user has left.
+func (m *RecoveryCodeModel) CountUnused(userId int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var count int
+	err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL", userId).Scan(&count)
+	return count, err
+}
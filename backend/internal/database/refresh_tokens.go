@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenNotFound is returned when no row matches the presented token.
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenExpired is returned when the token is past its expiry.
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	// ErrRefreshTokenRevoked is returned for tokens revoked by logout or
+	// session revocation.
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	// ErrRefreshTokenReused is returned when an already-rotated token is
+	// presented again. The whole family is revoked before this is returned.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+type RefreshTokenModel struct {
+	DB *sql.DB
+}
+
+// RefreshToken is a server-side record of an opaque refresh token. Only the
+// SHA-256 hash of the token is stored; the plaintext is handed to the client
+// once and never persisted.
+//
+// Tokens issued from the same login share a FamilyId and SessionId. Every
+// refresh rotates the token inside that family, which lets us spot a stolen
+// token being replayed after the legitimate client already rotated it.
+type RefreshToken struct {
+	Id         int
+	UserId     int
+	SessionId  int
+	FamilyId   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *int
+	CreatedAt  time.Time
+}
+
+func (m *RefreshTokenModel) Insert(token *RefreshToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return insertRefreshToken(ctx, m.DB, token)
+}
+
+// execQueryer is satisfied by both *sql.DB and *sql.Tx so helpers can run
+// inside or outside a transaction.
+type execQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertRefreshToken(ctx context.Context, q execQueryer, token *RefreshToken) error {
+	token.CreatedAt = time.Now().UTC()
+	query := `INSERT INTO refresh_tokens (user_id, session_id, family_id, token_hash, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	return q.QueryRowContext(ctx, query, token.UserId, token.SessionId, token.FamilyId, token.TokenHash, token.ExpiresAt.UTC(), token.CreatedAt).Scan(&token.Id)
+}
+
+func getRefreshTokenByHash(ctx context.Context, q execQueryer, hash string) (*RefreshToken, error) {
+	query := `SELECT id, user_id, COALESCE(session_id, 0), family_id, token_hash, expires_at, revoked_at, replaced_by, created_at
+	FROM refresh_tokens WHERE token_hash = $1`
+
+	var token RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullInt64
+	err := q.QueryRowContext(ctx, query, hash).Scan(&token.Id, &token.UserId, &token.SessionId, &token.FamilyId, &token.TokenHash,
+		&token.ExpiresAt, &revokedAt, &replacedBy, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	if replacedBy.Valid {
+		id := int(replacedBy.Int64)
+		token.ReplacedBy = &id
+	}
+	return &token, nil
+}
+
+func (m *RefreshTokenModel) GetByHash(hash string) (*RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return getRefreshTokenByHash(ctx, m.DB, hash)
+}
+
+// Rotate exchanges the token identified by oldHash for next. next inherits the
+// user, session and family of the old token. Presenting a token that was
+// already rotated is treated as theft: the entire family and its session are
+// revoked and ErrRefreshTokenReused is returned.
+func (m *RefreshTokenModel) Rotate(ctx context.Context, oldHash string, next *RefreshToken) (*RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := getRefreshTokenByHash(ctx, tx, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrRefreshTokenNotFound
+	}
+
+	now := time.Now().UTC()
+	if current.RevokedAt != nil && current.ReplacedBy == nil {
+		return current, ErrRefreshTokenRevoked
+	}
+	if current.RevokedAt != nil {
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`, now, current.FamilyId); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`, now, current.SessionId); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return current, ErrRefreshTokenReused
+	}
+	if now.After(current.ExpiresAt) {
+		return current, ErrRefreshTokenExpired
+	}
+
+	next.UserId = current.UserId
+	next.SessionId = current.SessionId
+	next.FamilyId = current.FamilyId
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = $1, replaced_by = $2 WHERE id = $3`, now, next.Id, current.Id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// RevokeFamily revokes every still-active token that shares familyId.
+func (m *RefreshTokenModel) RevokeFamily(ctx context.Context, familyId string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL"
+	_, err := m.DB.ExecContext(ctx, query, time.Now().UTC(), familyId)
+	return err
+}
+
+// RevokeAllForUser revokes every active refresh token the user holds.
+func (m *RefreshTokenModel) RevokeAllForUser(ctx context.Context, userId int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+	_, err := m.DB.ExecContext(ctx, query, time.Now().UTC(), userId)
+	return err
+}
@@ -0,0 +1,439 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Registration field types.
+const (
+	FieldText         = "text"
+	FieldNumber       = "number"
+	FieldBoolean      = "boolean"
+	FieldSingleChoice = "single_choice"
+	FieldMultiChoice  = "multi_choice"
+)
+
+// maxTextAnswer bounds free-text answers.
+const maxTextAnswer = 2000
+
+type RegistrationModel struct {
+	DB *sql.DB
+}
+
+// RegistrationField is one question of an event's registration form.
+// Options are the choices of single_choice and multi_choice fields.
+type RegistrationField struct {
+	Id       int      `json:"id"`
+	EventId  int      `json:"-"`
+	Label    string   `json:"label" binding:"required,max=200"`
+	Type     string   `json:"type" binding:"required,oneof=text number boolean single_choice multi_choice"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty" binding:"omitempty,dive,required,max=200"`
+	Position int      `json:"position"`
+}
+
+// Validate checks that the field's options fit its type.
+func (f *RegistrationField) Validate() error {
+	choice := f.Type == FieldSingleChoice || f.Type == FieldMultiChoice
+	switch {
+	case choice && len(f.Options) < 2:
+		return fmt.Errorf("field %q needs at least two options", f.Label)
+	case !choice && len(f.Options) > 0:
+		return fmt.Errorf("field %q is %s and cannot have options", f.Label, f.Type)
+	}
+	for i, option := range f.Options {
+		if slices.Contains(f.Options[:i], option) {
+			return fmt.Errorf("field %q lists option %q twice", f.Label, option)
+		}
+	}
+	return nil
+}
+
+// Check decodes an answer to the field and checks it. It returns the
+// value to store: a string, float64, bool or []string. null is not an
+// answer of any type.
+func (f *RegistrationField) Check(raw json.RawMessage) (any, error) {
+	var err error
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, fmt.Errorf("%q must be a %s answer", f.Label, strings.ReplaceAll(f.Type, "_", " "))
+	}
+	switch f.Type {
+	case FieldText:
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			s = strings.TrimSpace(s)
+			switch {
+			case len(s) > maxTextAnswer:
+				return nil, fmt.Errorf("%q must be at most %d characters", f.Label, maxTextAnswer)
+			case s == "" && f.Required:
+				return nil, fmt.Errorf("%q is required", f.Label)
+			}
+			return s, nil
+		}
+	case FieldNumber:
+		var n float64
+		if err = json.Unmarshal(raw, &n); err == nil {
+			return n, nil
+		}
+	case FieldBoolean:
+		var b bool
+		if err = json.Unmarshal(raw, &b); err == nil {
+			return b, nil
+		}
+	case FieldSingleChoice:
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			if !slices.Contains(f.Options, s) {
+				return nil, fmt.Errorf("%q must be one of %s", f.Label, strings.Join(f.Options, ", "))
+			}
+			return s, nil
+		}
+	case FieldMultiChoice:
+		var list []string
+		if err = json.Unmarshal(raw, &list); err == nil {
+			chosen := []string{}
+			for _, s := range list {
+				if !slices.Contains(f.Options, s) {
+					return nil, fmt.Errorf("%q must be chosen from %s", f.Label, strings.Join(f.Options, ", "))
+				}
+				if !slices.Contains(chosen, s) {
+					chosen = append(chosen, s)
+				}
+			}
+			if len(chosen) == 0 && f.Required {
+				return nil, fmt.Errorf("%q needs at least one choice", f.Label)
+			}
+			return chosen, nil
+		}
+	}
+	return nil, fmt.Errorf("%q must be a %s answer", f.Label, strings.ReplaceAll(f.Type, "_", " "))
+}
+
+// Answers are a registration's answers by field id.
+type Answers map[int]any
+
+// CheckAnswers validates submitted answers, keyed by field id, against the
+// form. With required set, required fields must be answered either in
+// submitted or in existing, the answers given before.
+func CheckAnswers(fields []*RegistrationField, submitted map[string]json.RawMessage, existing Answers, required bool) (Answers, error) {
+	byId := map[int]*RegistrationField{}
+	for _, f := range fields {
+		byId[f.Id] = f
+	}
+
+	answers := Answers{}
+	for key, raw := range submitted {
+		id, err := strconv.Atoi(key)
+		field := byId[id]
+		if err != nil || field == nil {
+			return nil, fmt.Errorf("there is no question %q on the registration form", key)
+		}
+		value, err := field.Check(raw)
+		if err != nil {
+			return nil, err
+		}
+		answers[id] = value
+	}
+
+	if required {
+		for _, f := range fields {
+			_, answered := answers[f.Id]
+			_, answeredBefore := existing[f.Id]
+			if f.Required && !answered && !answeredBefore {
+				return nil, fmt.Errorf("%q is required", f.Label)
+			}
+		}
+	}
+	return answers, nil
+}
+
+// Fields returns the event's registration form, in order.
+func (m *RegistrationModel) Fields(eventId int) ([]*RegistrationField, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return registrationFields(ctx, m.DB, eventId)
+}
+
+func registrationFields(ctx context.Context, q execQueryer, eventId int) ([]*RegistrationField, error) {
+	query := "SELECT id, event_id, label, type, required, options, position FROM registration_fields WHERE event_id = $1 ORDER BY position, id"
+	rows, err := q.QueryContext(ctx, query, eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []*RegistrationField{}
+	for rows.Next() {
+		var f RegistrationField
+		var options sql.NullString
+		if err := rows.Scan(&f.Id, &f.EventId, &f.Label, &f.Type, &f.Required, &options, &f.Position); err != nil {
+			return nil, err
+		}
+		if options.Valid {
+			if err := json.Unmarshal([]byte(options.String), &f.Options); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, &f)
+	}
+	return fields, rows.Err()
+}
+
+// SetFields replaces the event's form with fields, in that order. Fields
+// with the id of an existing field update it and keep its answers, unless
+// the type changes; fields left out are removed with their answers.
+func (m *RegistrationModel) SetFields(ctx context.Context, eventId int, fields []*RegistrationField) ([]*RegistrationField, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing, err := registrationFields(ctx, tx, eventId)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[int]*RegistrationField{}
+	for _, f := range existing {
+		byId[f.Id] = f
+	}
+
+	kept := map[int]bool{}
+	for i, f := range fields {
+		f.EventId, f.Position = eventId, i
+		var options any
+		if len(f.Options) > 0 {
+			data, err := json.Marshal(f.Options)
+			if err != nil {
+				return nil, err
+			}
+			options = string(data)
+		}
+
+		old, ok := byId[f.Id]
+		if !ok {
+			query := `INSERT INTO registration_fields (event_id, label, type, required, options, position, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+			if err := tx.QueryRowContext(ctx, query, eventId, f.Label, f.Type, f.Required, options, f.Position, time.Now().UTC()).Scan(&f.Id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		kept[f.Id] = true
+		query := "UPDATE registration_fields SET label = $1, type = $2, required = $3, options = $4, position = $5 WHERE id = $6"
+		if _, err := tx.ExecContext(ctx, query, f.Label, f.Type, f.Required, options, f.Position, f.Id); err != nil {
+			return nil, err
+		}
+		if old.Type != f.Type {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM registration_answers WHERE field_id = $1", f.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range existing {
+		if kept[f.Id] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM registration_answers WHERE field_id = $1", f.Id); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM registration_fields WHERE id = $1", f.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Answers returns the answers given with an RSVP.
+func (m *RegistrationModel) Answers(attendeeId int) (Answers, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, "SELECT field_id, value FROM registration_answers WHERE attendee_id = $1", attendeeId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	answers := Answers{}
+	for rows.Next() {
+		var fieldId int
+		var value string
+		if err := rows.Scan(&fieldId, &value); err != nil {
+			return nil, err
+		}
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, err
+		}
+		answers[fieldId] = v
+	}
+	return answers, rows.Err()
+}
+
+// saveAnswers stores answers for an RSVP, replacing earlier answers to the
+// same questions.
+func saveAnswers(ctx context.Context, q execQueryer, attendeeId int, answers Answers) error {
+	query := `INSERT INTO registration_answers (attendee_id, field_id, value, updated_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (attendee_id, field_id) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+
+	now := time.Now().UTC()
+	for fieldId, value := range answers {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, query, attendeeId, fieldId, string(data), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAnswers removes the answers of the RSVPs matching where (on
+// attendees, with args as $1...), before the RSVPs themselves go.
+func deleteAnswers(ctx context.Context, q execQueryer, where string, args ...any) error {
+	_, err := q.ExecContext(ctx, "DELETE FROM registration_answers WHERE attendee_id IN (SELECT id FROM attendees WHERE "+where+")", args...)
+	return err
+}
+
+// AttendeeAnswers are one person's answers to an event's form.
+type AttendeeAnswers struct {
+	AttendeeId int     `json:"attendeeId"`
+	UserId     int     `json:"userId"`
+	Name       string  `json:"name"`
+	Email      string  `json:"email"`
+	Status     string  `json:"status"`
+	Answers    Answers `json:"answers"`
+}
+
+// EventAnswers lists the answers of everyone going to or waitlisted for the
+// event, or one occurrence of it, in the order they joined.
+func (m *RegistrationModel) EventAnswers(eventId int, occurrence string) ([]*AttendeeAnswers, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT a.id, u.id, u.name, u.email, a.status FROM attendees a JOIN users u ON u.id = a.user_id
+	WHERE a.event_id = $1 AND a.occurrence = $2 AND a.status IN ('going', 'waitlisted') ORDER BY a.id`
+	rows, err := m.DB.QueryContext(ctx, query, eventId, occurrence)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*AttendeeAnswers{}
+	byAttendee := map[int]*AttendeeAnswers{}
+	for rows.Next() {
+		aa := AttendeeAnswers{Answers: Answers{}}
+		if err := rows.Scan(&aa.AttendeeId, &aa.UserId, &aa.Name, &aa.Email, &aa.Status); err != nil {
+			return nil, err
+		}
+		list = append(list, &aa)
+		byAttendee[aa.AttendeeId] = &aa
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT r.attendee_id, r.field_id, r.value FROM registration_answers r JOIN attendees a ON a.id = r.attendee_id
+	WHERE a.event_id = $1 AND a.occurrence = $2`
+	answerRows, err := m.DB.QueryContext(ctx, query, eventId, occurrence)
+	if err != nil {
+		return nil, err
+	}
+	defer answerRows.Close()
+
+	for answerRows.Next() {
+		var attendeeId, fieldId int
+		var value string
+		if err := answerRows.Scan(&attendeeId, &fieldId, &value); err != nil {
+			return nil, err
+		}
+		aa, ok := byAttendee[attendeeId]
+		if !ok {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, err
+		}
+		aa.Answers[fieldId] = v
+	}
+	return list, answerRows.Err()
+}
+
+// FieldSummary adds up the answers to one question. Counts has how often
+// each answer was given, for every type but number; numbers get Min, Max,
+// Sum and Average instead.
+type FieldSummary struct {
+	Field    *RegistrationField `json:"field"`
+	Answered int                `json:"answered"`
+	Counts   map[string]int     `json:"counts,omitempty"`
+	Min      *float64           `json:"min,omitempty"`
+	Max      *float64           `json:"max,omitempty"`
+	Sum      *float64           `json:"sum,omitempty"`
+	Average  *float64           `json:"average,omitempty"`
+}
+
+// Summarize adds up the answers to every question of the form.
+func Summarize(fields []*RegistrationField, list []*AttendeeAnswers) []*FieldSummary {
+	summaries := make([]*FieldSummary, len(fields))
+	for i, f := range fields {
+		s := &FieldSummary{Field: f}
+		if f.Type != FieldNumber {
+			s.Counts = map[string]int{}
+			for _, option := range f.Options {
+				s.Counts[option] = 0
+			}
+		}
+
+		var sum, lo, hi float64
+		for _, aa := range list {
+			value, ok := aa.Answers[f.Id]
+			if !ok {
+				continue
+			}
+			s.Answered++
+			switch v := value.(type) {
+			case float64:
+				if s.Answered == 1 || v < lo {
+					lo = v
+				}
+				if s.Answered == 1 || v > hi {
+					hi = v
+				}
+				sum += v
+			case []any:
+				for _, choice := range v {
+					s.Counts[fmt.Sprint(choice)]++
+				}
+			default:
+				s.Counts[fmt.Sprint(v)]++
+			}
+		}
+		if f.Type == FieldNumber && s.Answered > 0 {
+			avg := sum / float64(s.Answered)
+			s.Min, s.Max, s.Sum, s.Average = &lo, &hi, &sum, &avg
+		}
+		summaries[i] = s
+	}
+	return summaries
+}
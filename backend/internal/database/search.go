@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxSearchTerms caps how many words of a search are used.
+const maxSearchTerms = 16
+
+// EventSearchResult is an event matching a search. Higher scores are better
+// matches. Snippet is an HTML-escaped excerpt of the best matching field with
+// the matched words wrapped in <mark>.
+type EventSearchResult struct {
+	*Event
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchQuery turns user input into an FTS5 query matching events that
+// contain every word, or a word starting with it. Each word is quoted, so
+// FTS5 syntax in the input (operators, column filters, parentheses) is taken
+// literally. It returns "" when the input has nothing to search for.
+func SearchQuery(input string) string {
+	var terms []string
+	for _, word := range strings.Fields(input) {
+		if !strings.ContainsFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+			continue
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search finds events by the words in name, description and location,
+// ranked with bm25 and names weighing most. Only public events and events
+// userId organizes are searched. input is raw user text; see SearchQuery.
+func (m *EventModel) Search(input string, userId, limit int) ([]*EventSearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	results := []*EventSearchResult{}
+	match := SearchQuery(input)
+	if match == "" {
+		return results, nil
+	}
+
+	// snippet() marks matches with control characters so the text can be
+	// escaped before they become <mark> tags
+	query := "SELECT " + eventColumns("e") + `,
+		bm25(events_fts, 10.0, 2.0, 5.0) AS rank,
+		snippet(events_fts, -1, char(2), char(3), '…', 12)
+	FROM events_fts JOIN events e ON e.id = events_fts.rowid
+	WHERE events_fts MATCH $1
+	AND (e.visibility = 'public' OR EXISTS (
+		SELECT 1 FROM event_organizers o WHERE o.event_id = e.id AND o.user_id = $2))
+	ORDER BY rank
+	LIMIT $3`
+
+	rows, err := m.DB.QueryContext(ctx, query, match, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	highlight := strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+	for rows.Next() {
+		var rank float64
+		var snippet string
+		event, keep, err := scanListedEvent(func(dest ...any) error {
+			return rows.Scan(append(dest, &rank, &snippet)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+		results = append(results, &EventSearchResult{
+			Event:   event,
+			Score:   -rank,
+			Snippet: highlight.Replace(html.EscapeString(snippet)),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"  \t ", ""},
+		{"berlin", `"berlin"*`},
+		{"  Go   meetup ", `"Go"* "meetup"*`},
+		// Operators are words like any other
+		{"meetup OR NOT party", `"meetup"* "OR"* "NOT"* "party"*`},
+		{"a AND b", `"a"* "AND"* "b"*`},
+		{"NEAR(go rust)", `"NEAR(go"* "rust)"*`},
+		// Quotes are doubled inside the quoted term
+		{`"go`, `"""go"*`},
+		{`say "hi"`, `"say"* """hi"""*`},
+		// Column filters, prefixes and parentheses stay inside the term
+		{"name:go", `"name:go"*`},
+		{"{name location}:go", `"{name"* "location}:go"*`},
+		{"meet* ^go -rust +c", `"meet*"* "^go"* "-rust"* "+c"*`},
+		// Words without letters or digits would only confuse FTS5
+		{`* " - ( ) :`, ""},
+		{"über 2025", `"über"* "2025"*`},
+	}
+	for _, tt := range tests {
+		if got := SearchQuery(tt.input); got != tt.want {
+			t.Errorf("SearchQuery(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	long := SearchQuery(strings.Repeat("word ", maxSearchTerms+5))
+	if terms := strings.Count(long, `"word"*`); terms != maxSearchTerms {
+		t.Errorf("long input kept %d terms, want %d", terms, maxSearchTerms)
+	}
+}
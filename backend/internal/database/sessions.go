@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+// Session represents one login on one device. Every access token carries the
+// session id in its "sid" claim, and every refresh token belongs to exactly
+// one session, so revoking a session cuts off both.
+type Session struct {
+	Id         int        `json:"id"`
+	UserId     int        `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+func (m *SessionModel) Insert(session *Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+
+	query := `INSERT INTO sessions (user_id, user_agent, ip_address, created_at, last_seen_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	return m.DB.QueryRowContext(ctx, query, session.UserId, session.UserAgent, session.IPAddress, session.CreatedAt, session.LastSeenAt).Scan(&session.Id)
+}
+
+func scanSession(scan func(dest ...any) error) (*Session, error) {
+	var session Session
+	var revokedAt sql.NullTime
+	if err := scan(&session.Id, &session.UserId, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastSeenAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return &session, nil
+}
+
+func (m *SessionModel) Get(id int) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at
+	FROM sessions WHERE id = $1`
+
+	session, err := scanSession(m.DB.QueryRowContext(ctx, query, id).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetActiveByUser lists the user's sessions that have not been revoked,
+// most recently used first.
+func (m *SessionModel) GetActiveByUser(userId int) ([]*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at
+	FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC`
+
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session, err := scanSession(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Touch records activity on the session.
+func (m *SessionModel) Touch(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "UPDATE sessions SET last_seen_at = $1 WHERE id = $2", time.Now().UTC(), id)
+	return err
+}
+
+// Revoke ends a single session belonging to userId together with its refresh
+// tokens. It returns sql.ErrNoRows when the user has no such active session.
+func (m *SessionModel) Revoke(ctx context.Context, userId, id int) error {
+	return m.revokeWhere(ctx, "user_id = $2 AND id = $3", userId, id)
+}
+
+func (m *SessionModel) revokeWhere(ctx context.Context, where string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	affected, err := revokeSessions(ctx, tx, where, args...)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// revokeSessions ends the active sessions matching where, whose placeholders
+// start at $2, together with their refresh tokens. It returns how many
+// sessions it ended.
+func revokeSessions(ctx context.Context, q execQueryer, where string, args ...any) (int64, error) {
+	args = append([]any{time.Now().UTC()}, args...)
+
+	result, err := q.ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE revoked_at IS NULL AND "+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return 0, err
+	}
+
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE revoked_at IS NULL AND session_id IN (SELECT id FROM sessions WHERE " + where + ")"
+	if _, err := q.ExecContext(ctx, query, args...); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
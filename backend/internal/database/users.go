@@ -13,6 +13,20 @@ type UserModel struct {
 	DB *sql.DB
 }
 
+// Roles, from least to most privileged. Moderators can see and remove any
+// event and disable ordinary accounts; admins can do everything, including
+// changing roles.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
+
 type User struct {
 	Id    int    `json:"id"`
 	Email string `json:"email"`
@@ -20,10 +34,30 @@ type User struct {
 
 	// tells JSON library to always ignore this field when converting the struct back into JSON
 	// to prevent from accidentally sending a user's password hash back to the client.
-	Password       string    `json:"-"`
-	ProfilePicture *string   `json:"profile_picture,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	Password        string     `json:"-"`
+	ProfilePicture  *string    `json:"profile_picture,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// TOTPSecret is set, encrypted with totp.SecretBox, once 2FA setup
+	// starts; it only counts once TwoFactorEnabledAt is set after the user
+	// confirmed a code.
+	TOTPSecret         string     `json:"-"`
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at"`
+	Role               string     `json:"role"`
+	DisabledAt         *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// HasRole reports whether the user has role or a more privileged one.
+func (u *User) HasRole(role string) bool {
+	rank := map[string]int{RoleUser: 0, RoleModerator: 1, RoleAdmin: 2}
+	return rank[u.Role] >= rank[role]
+}
+
+// Disabled reports whether an administrator has disabled the account.
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
 }
 
 type UpdateUserParams struct {
@@ -31,6 +65,10 @@ type UpdateUserParams struct {
 	Email          *string
 	PasswordHash   []byte
 	ProfilePicture *string
+
+	// KeepSessionId is the session that stays signed in when PasswordHash
+	// changes; 0 signs out all of them.
+	KeepSessionId int
 }
 
 var defaultTimeout = 3 * time.Second
@@ -39,22 +77,23 @@ func (m *UserModel) Insert(user *User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	query := "INSERT INTO users (email, password, name) VALUES ($1, $2, $3) RETURNING id"
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+	query := "INSERT INTO users (email, password, name, role) VALUES ($1, $2, $3, $4) RETURNING id"
 
-	return m.DB.QueryRowContext(ctx, query, user.Email, user.Password, user.Name).Scan(&user.Id)
+	return m.DB.QueryRowContext(ctx, query, user.Email, user.Password, user.Name, user.Role).Scan(&user.Id)
 }
 
-func (m *UserModel) getUser(query string, args ...interface{}) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
+// userColumns is the column list scanUser expects, in order.
+const userColumns = "id, email, name, password, profile_picture, email_verified_at, totp_secret, totp_enabled_at, role, disabled_at"
 
+func scanUser(scan func(dest ...any) error) (*User, error) {
 	var user User
-	var profile sql.NullString
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Id, &user.Email, &user.Name, &user.Password, &profile)
+	var profile, totpSecret sql.NullString
+	var verifiedAt, totpEnabledAt, disabledAt sql.NullTime
+	err := scan(&user.Id, &user.Email, &user.Name, &user.Password, &profile, &verifiedAt, &totpSecret, &totpEnabledAt, &user.Role, &disabledAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
 	if profile.Valid {
@@ -62,19 +101,141 @@ func (m *UserModel) getUser(query string, args ...interface{}) (*User, error) {
 	} else {
 		user.ProfilePicture = nil
 	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	user.TOTPSecret = totpSecret.String
+	if totpEnabledAt.Valid {
+		user.TwoFactorEnabledAt = &totpEnabledAt.Time
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
 	return &user, nil
 }
 
+func (m *UserModel) getUser(query string, args ...interface{}) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	user, err := scanUser(m.DB.QueryRowContext(ctx, query, args...).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 func (m *UserModel) GetUserByID(id int) (*User, error) {
-	query := "SELECT id, email, name, password, profile_picture FROM users WHERE id = $1"
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
 	return m.getUser(query, id)
 }
 
 func (m *UserModel) GetByEmail(email string) (*User, error) {
-	query := "SELECT id, email, name, password, profile_picture FROM users WHERE email = $1"
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
 	return m.getUser(query, email)
 }
 
+// Search lists users whose email or name contains query (all users when
+// query is empty), ordered by id.
+func (m *UserModel) Search(query string, limit, offset int) ([]*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	pattern := "%" + escapeLike(query) + "%"
+	sqlQuery := "SELECT " + userColumns + ` FROM users
+	WHERE email LIKE $1 ESCAPE '\' OR name LIKE $1 ESCAPE '\'
+	ORDER BY id LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.QueryContext(ctx, sqlQuery, pattern, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// SetRole changes the user's role. It returns sql.ErrNoRows for unknown users.
+func (m *UserModel) SetRole(ctx context.Context, id int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// SetDisabled disables or re-enables an account. Disabling also revokes all
+// of the user's sessions and refresh tokens so existing logins end at once.
+func (m *UserModel) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var disabledAt *time.Time
+	if disabled {
+		disabledAt = &now
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET disabled_at = $1 WHERE id = $2", disabledAt, id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+
+	if disabled {
+		if _, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// requireAffected turns an update that matched no rows into sql.ErrNoRows.
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Update changes the fields set in params. A new password also revokes every
+// session but params.KeepSessionId in the same transaction, so a leaked
+// password or stolen device loses access once the owner reacts. API keys are
+// left alone; they are managed separately.
 func (m *UserModel) Update(ctx context.Context, id int, params UpdateUserParams) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
@@ -87,8 +248,9 @@ func (m *UserModel) Update(ctx context.Context, id int, params UpdateUserParams)
 		args = append(args, strings.TrimSpace(*params.Name))
 	}
 	if params.Email != nil {
-		setClauses = append(setClauses, "email = ?")
-		args = append(args, strings.TrimSpace(*params.Email))
+		// A changed address has to be verified again
+		setClauses = append(setClauses, "email_verified_at = CASE WHEN email = ? THEN email_verified_at ELSE NULL END", "email = ?")
+		args = append(args, strings.TrimSpace(*params.Email), strings.TrimSpace(*params.Email))
 	}
 	if len(params.PasswordHash) > 0 {
 		setClauses = append(setClauses, "password = ?")
@@ -108,15 +270,134 @@ func (m *UserModel) Update(ctx context.Context, id int, params UpdateUserParams)
 		return m.GetUserByID(id)
 	}
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := fmt.Sprintf("UPDATE users SET %s WHERE id = ?", strings.Join(setClauses, ", "))
 	args = append(args, id)
-	if _, err := m.DB.ExecContext(ctx, query, args...); err != nil {
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
 		return nil, err
 	}
 
+	if len(params.PasswordHash) > 0 {
+		if _, err := revokeSessions(ctx, tx, "user_id = $2 AND id != $3", id, params.KeepSessionId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return m.GetUserByID(id)
 }
 
+// MarkEmailVerified records that the user proved ownership of email. It only
+// succeeds while the account still uses that address and is unverified, which
+// makes each verification token single-use. The returned bool reports whether
+// a row changed.
+func (m *UserModel) MarkEmailVerified(ctx context.Context, id int, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE users SET email_verified_at = $1 WHERE id = $2 AND email = $3 AND email_verified_at IS NULL"
+	result, err := m.DB.ExecContext(ctx, query, time.Now().UTC(), id, email)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// SetPendingTOTPSecret stores a new secret for a user who has not enabled 2FA
+// yet. It returns false when 2FA is already enabled.
+func (m *UserModel) SetPendingTOTPSecret(ctx context.Context, id int, secret string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE users SET totp_secret = $1, totp_last_counter = 0 WHERE id = $2 AND totp_enabled_at IS NULL"
+	result, err := m.DB.ExecContext(ctx, query, secret, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// EnableTOTP switches 2FA on and replaces the user's recovery codes in one
+// transaction. counter is the time step of the code that confirmed setup.
+func (m *UserModel) EnableTOTP(ctx context.Context, id int, counter int64, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET totp_enabled_at = $1, totp_last_counter = $2 WHERE id = $3", now, counter, id); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, id, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableTOTP removes the secret and all recovery codes.
+func (m *UserModel) DisableTOTP(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_counter = 0 WHERE id = $1", id); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, id, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceTOTPSecret swaps the user's stored TOTP secret old for new, the
+// same secret in another form. It does nothing when the secret changed in
+// the meantime.
+func (m *UserModel) ReplaceTOTPSecret(ctx context.Context, id int, old, new string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "UPDATE users SET totp_secret = $1 WHERE id = $2 AND totp_secret = $3", new, id, old)
+	return err
+}
+
+// ConsumeTOTPCounter records counter as the last used time step. It returns
+// false when a code for this or a later step was already accepted, so every
+// code works at most once.
+func (m *UserModel) ConsumeTOTPCounter(ctx context.Context, id int, counter int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := "UPDATE users SET totp_last_counter = $1 WHERE id = $2 AND totp_last_counter < $1"
+	result, err := m.DB.ExecContext(ctx, query, counter, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
 func (m *UserModel) Delete(ctx context.Context, id int) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
@@ -126,16 +407,104 @@ func (m *UserModel) Delete(ctx context.Context, id int) error {
 		return err
 	}
 
+	// Deleting the user's events cancels them in everybody else's feed
+	if err := recordCancellations(ctx, tx, "e.owner_id = $1", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := deleteAnswers(ctx, tx, "user_id = $1 OR event_id IN (SELECT id FROM events WHERE owner_id = $1)", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := deleteCheckIns(ctx, tx, "user_id = $1 OR event_id IN (SELECT id FROM events WHERE owner_id = $1)", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM registration_fields WHERE event_id IN (SELECT id FROM events WHERE owner_id = $1)`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if _, err := tx.ExecContext(ctx, `DELETE FROM attendees WHERE event_id IN (SELECT id FROM events WHERE owner_id = $1)`, id); err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	// The user's own RSVPs go too; their spots are handed to the waitlists
+	freed, err := collectFreed(tx.QueryContext(ctx, `DELETE FROM attendees WHERE user_id = $1 RETURNING event_id, occurrence, status`, id))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, f := range freed {
+		if _, err := promoteWaitlisted(ctx, tx, f.eventId, f.occurrence); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_organizers WHERE user_id = $1 OR event_id IN (SELECT id FROM events WHERE owner_id = $1)`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM invitations WHERE created_by = $1 OR event_id IN (SELECT id FROM events WHERE owner_id = $1)`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_occurrences WHERE event_id IN (SELECT id FROM events WHERE owner_id = $1)`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if _, err := tx.ExecContext(ctx, `DELETE FROM events WHERE owner_id = $1`, id); err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM password_reset_tokens WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_identities WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM calendar_cancellations WHERE user_id = $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
 	if err != nil {
 		tx.Rollback()
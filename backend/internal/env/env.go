@@ -3,6 +3,7 @@ package env
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetEnvString(key, defaultValue string) string {
@@ -20,4 +21,24 @@ func GetEnvInt(key string, defaultValue int) int{
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// GetEnvDuration reads a Go duration string such as "15m" or "720h".
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvBool accepts the values understood by strconv.ParseBool ("true", "0", ...).
+func GetEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,303 @@
+// Package ical writes RFC 5545 iCalendar files: a VCALENDAR with VEVENTs,
+// plus the VTIMEZONEs the events refer to, so calendar applications show
+// them at the right local time.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const (
+	utcFormat   = "20060102T150405Z"
+	localFormat = "20060102T150405"
+)
+
+// Calendar is one .ics file.
+type Calendar struct {
+	ProdID string
+	// Name is shown by clients that subscribe to the calendar; it may be
+	// empty for a single event download.
+	Name   string
+	Events []*Event
+}
+
+// Event is one VEVENT. Times are written in TimeZone when it is set and not
+// UTC, and in UTC otherwise. RecurrenceID marks the event as the changed
+// version of the occurrence of UID's series that originally started then.
+type Event struct {
+	UID          string
+	Sequence     int
+	Stamp        time.Time
+	Start        time.Time
+	End          time.Time
+	TimeZone     *time.Location
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Status       string
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time
+}
+
+func (e *Event) zoned() bool {
+	return e.TimeZone != nil && e.TimeZone != time.UTC && e.TimeZone.String() != "UTC"
+}
+
+// WriteTo writes the calendar to w.
+func (cal *Calendar) WriteTo(w io.Writer) (int64, error) {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.prop("PRODID", cal.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		lw.prop("X-WR-CALNAME", cal.Name)
+	}
+	for _, tz := range cal.timeZones() {
+		tz.write(lw)
+	}
+	for _, e := range cal.Events {
+		e.write(lw)
+	}
+	lw.line("END:VCALENDAR")
+
+	if lw.err == nil {
+		lw.err = lw.w.Flush()
+	}
+	return lw.n, lw.err
+}
+
+func (e *Event) write(lw *lineWriter) {
+	lw.line("BEGIN:VEVENT")
+	lw.prop("UID", e.UID)
+	lw.line("DTSTAMP:" + e.Stamp.UTC().Format(utcFormat))
+	lw.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	if !e.RecurrenceID.IsZero() {
+		lw.line(e.timeProp("RECURRENCE-ID", e.RecurrenceID))
+	}
+	lw.line(e.timeProp("DTSTART", e.Start))
+	lw.line(e.timeProp("DTEND", e.End))
+	if e.RRule != "" {
+		lw.line("RRULE:" + e.RRule)
+	}
+	if len(e.ExDates) > 0 {
+		lw.line(e.timeProp("EXDATE", e.ExDates...))
+	}
+	lw.prop("SUMMARY", e.Summary)
+	if e.Description != "" {
+		lw.prop("DESCRIPTION", e.Description)
+	}
+	if e.Location != "" {
+		lw.prop("LOCATION", e.Location)
+	}
+	if e.URL != "" {
+		lw.line("URL:" + e.URL)
+	}
+	status := e.Status
+	if status == "" {
+		status = StatusConfirmed
+	}
+	lw.line("STATUS:" + status)
+	lw.line("END:VEVENT")
+}
+
+// timeProp formats a date-time property with one or more values.
+func (e *Event) timeProp(name string, times ...time.Time) string {
+	values := make([]string, len(times))
+	for i, t := range times {
+		if e.zoned() {
+			values[i] = t.In(e.TimeZone).Format(localFormat)
+		} else {
+			values[i] = t.UTC().Format(utcFormat)
+		}
+	}
+	if e.zoned() {
+		name += ";TZID=" + e.TimeZone.String()
+	}
+	return name + ":" + strings.Join(values, ",")
+}
+
+// timeZone is a VTIMEZONE covering the years [from, to).
+type timeZone struct {
+	loc      *time.Location
+	from, to int
+}
+
+// timeZones returns the zones the events use, covering the years from their
+// earliest start to five years past the latest one or now, whichever is
+// later, so repeating events show correctly for a while.
+func (cal *Calendar) timeZones() []*timeZone {
+	zones := map[string]*timeZone{}
+	for _, e := range cal.Events {
+		if !e.zoned() {
+			continue
+		}
+		year := e.Start.In(e.TimeZone).Year()
+		tz, ok := zones[e.TimeZone.String()]
+		if !ok {
+			tz = &timeZone{loc: e.TimeZone, from: year, to: max(year, time.Now().Year()) + 5}
+			zones[e.TimeZone.String()] = tz
+		}
+		tz.from = min(tz.from, year)
+		tz.to = max(tz.to, year+5)
+	}
+
+	out := make([]*timeZone, 0, len(zones))
+	for _, tz := range zones {
+		out = append(out, tz)
+	}
+	slices.SortFunc(out, func(a, b *timeZone) int { return strings.Compare(a.loc.String(), b.loc.String()) })
+	return out
+}
+
+// transition is a change of UTC offset.
+type transition struct {
+	at       time.Time
+	from, to int
+}
+
+// transitions finds the offset changes of the zone by looking at every day
+// and narrowing down to the second where the offset differs.
+func (tz *timeZone) transitions() []transition {
+	offset := func(t time.Time) int {
+		_, off := t.In(tz.loc).Zone()
+		return off
+	}
+
+	var out []transition
+	day := time.Date(tz.from, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(tz.to, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for ; day.Before(end); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		before, after := offset(day), offset(next)
+		if before == after {
+			continue
+		}
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if offset(mid) == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		out = append(out, transition{at: hi, from: before, to: after})
+	}
+	return out
+}
+
+func (tz *timeZone) write(lw *lineWriter) {
+	lw.line("BEGIN:VTIMEZONE")
+	lw.prop("TZID", tz.loc.String())
+
+	start := time.Date(tz.from, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, off := start.In(tz.loc).Zone()
+	tz.observance(lw, start, off, off)
+	for _, tr := range tz.transitions() {
+		tz.observance(lw, tr.at, tr.from, tr.to)
+	}
+
+	lw.line("END:VTIMEZONE")
+}
+
+// observance writes a STANDARD or DAYLIGHT block for the offset taking
+// effect at at. Its DTSTART is local time in the offset before.
+func (tz *timeZone) observance(lw *lineWriter, at time.Time, from, to int) {
+	local := at.In(tz.loc)
+	kind := "STANDARD"
+	if local.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, _ := local.Zone()
+
+	lw.line("BEGIN:" + kind)
+	lw.line("DTSTART:" + at.In(time.FixedZone("", from)).Format(localFormat))
+	lw.line("TZOFFSETFROM:" + formatOffset(from))
+	lw.line("TZOFFSETTO:" + formatOffset(to))
+	lw.prop("TZNAME", name)
+	lw.line("END:" + kind)
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
+
+// lineWriter writes content lines, folded at 75 octets and ended with CRLF.
+// The first error sticks.
+type lineWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// prop writes a property with a text value.
+func (lw *lineWriter) prop(name, value string) {
+	lw.line(name + ":" + escapeText(value))
+}
+
+func (lw *lineWriter) line(s string) {
+	const limit = 75
+	for first := true; lw.err == nil; first = false {
+		width := limit
+		if !first {
+			// Continuation lines start with a space
+			width--
+		}
+		cut := len(s)
+		if cut > width {
+			// Never split a UTF-8 sequence
+			cut = width
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+		}
+		if !first {
+			lw.write(" ")
+		}
+		lw.write(s[:cut] + "\r\n")
+		s = s[cut:]
+		if s == "" {
+			return
+		}
+	}
+}
+
+func (lw *lineWriter) write(s string) {
+	if lw.err != nil {
+		return
+	}
+	n, err := lw.w.WriteString(s)
+	lw.n += int64(n)
+	lw.err = err
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,323 @@
+// Package jwtkeys manages the keys used to sign and verify the API's JWTs.
+//
+// A KeySet has exactly one signing key and any number of verification keys.
+// Keeping the previous public key in the verification set while a new private
+// key signs lets keys rotate without logging everybody out. Asymmetric keys
+// (RS256, EdDSA) are published as a JWKS document so other services can verify
+// tokens without holding any secret.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Key is a single JWT key. Private is nil for verification-only keys.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+	secret  []byte
+}
+
+// NewHMACKey wraps a shared secret for HS256. HMAC keys are never published.
+func NewHMACKey(secret string) *Key {
+	return &Key{ID: "hs256", Method: jwt.SigningMethodHS256, secret: []byte(secret)}
+}
+
+// LoadPrivateKeyFile reads an RSA or Ed25519 private key from a PEM file.
+// PKCS#8 and PKCS#1 ("RSA PRIVATE KEY") encodings are accepted.
+func LoadPrivateKeyFile(path string) (*Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return newKey(jwt.SigningMethodRS256, k, &k.PublicKey)
+	case ed25519.PrivateKey:
+		return newKey(jwt.SigningMethodEdDSA, k, k.Public())
+	default:
+		return nil, fmt.Errorf("%s: unsupported private key type %T", path, parsed)
+	}
+}
+
+// LoadPublicKeyFile reads an RSA or Ed25519 public key from a PEM file. A
+// private key file is also accepted, in which case only its public half is
+// kept.
+func LoadPublicKeyFile(path string) (*Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type != "PUBLIC KEY" {
+		key, err := LoadPrivateKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key.Private = nil
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewPublicKey(parsed)
+}
+
+// NewPublicKey builds a verification-only key from an already parsed public key.
+func NewPublicKey(pub crypto.PublicKey) (*Key, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return newKey(jwt.SigningMethodRS256, nil, k)
+	case ed25519.PublicKey:
+		return newKey(jwt.SigningMethodEdDSA, nil, k)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+func newKey(method jwt.SigningMethod, private crypto.PrivateKey, public crypto.PublicKey) (*Key, error) {
+	key := &Key{Method: method, Private: private, Public: public}
+	kid, err := thumbprint(key.JWK())
+	if err != nil {
+		return nil, err
+	}
+	key.ID = kid
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
+
+// JWK is the public JSON Web Key representation of a key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWK returns the public JWK for an asymmetric key. HMAC keys have no public
+// form and return the zero value.
+func (k *Key) JWK() JWK {
+	enc := base64.RawURLEncoding
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA", Kid: k.ID, Use: "sig", Alg: k.Method.Alg(),
+			N: enc.EncodeToString(pub.N.Bytes()),
+			E: enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: k.ID, Use: "sig", Alg: k.Method.Alg(), Crv: "Ed25519", X: enc.EncodeToString(pub)}
+	}
+	return JWK{}
+}
+
+// PublicKey converts a JWK back into a verification key. It is the inverse of
+// Key.JWK and is used for keys published by other issuers.
+func (j JWK) PublicKey() (*Key, error) {
+	enc := base64.RawURLEncoding
+	var pub crypto.PublicKey
+	switch j.Kty {
+	case "RSA":
+		n, err := enc.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := enc.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := enc.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key length")
+		}
+		pub = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+
+	key, err := NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if j.Kid != "" {
+		key.ID = j.Kid
+	}
+	return key, nil
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint used as the key id, so the
+// same key always gets the same kid no matter which file it was loaded from.
+func thumbprint(j JWK) (string, error) {
+	var members any
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// KeySet signs with one key and verifies against all of its keys.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet creates a set that signs with signing. Additional verification
+// keys (for example the previous key during a rotation) can be passed in
+// verify; the signing key is always part of the verification set.
+func NewKeySet(signing *Key, verify ...*Key) (*KeySet, error) {
+	if signing == nil {
+		return nil, errors.New("jwtkeys: signing key is required")
+	}
+	if signing.Private == nil && signing.secret == nil {
+		return nil, errors.New("jwtkeys: signing key has no private part")
+	}
+
+	set := &KeySet{signing: signing, keys: map[string]*Key{signing.ID: signing}}
+	for _, key := range verify {
+		if key.secret != nil {
+			return nil, errors.New("jwtkeys: HMAC keys cannot be added as verification keys")
+		}
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+// SigningKey returns the key new tokens are signed with.
+func (s *KeySet) SigningKey() *Key {
+	return s.signing
+}
+
+// Sign signs claims with the current signing key and sets the kid header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+
+	if s.signing.secret != nil {
+		return token.SignedString(s.signing.secret)
+	}
+	return token.SignedString(s.signing.Private)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header and
+// rejects tokens whose alg does not match that key, which rules out
+// algorithm substitution attacks. Tokens without a kid are only accepted when
+// the set signs with HMAC, since tokens issued before kids existed were HS256.
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := s.keys[kid]
+	if !ok && kid == "" && s.signing.secret != nil {
+		key, ok = s.signing, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	if key.secret != nil {
+		return key.secret, nil
+	}
+	return key.Public, nil
+}
+
+// JWKS is a JSON Web Key Set document.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. HMAC keys are never included.
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		if key.secret != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, key.JWK())
+	}
+
+	// Stable output: the signing key first, the rest by kid
+	sort.Slice(set.Keys, func(i, j int) bool {
+		if (set.Keys[i].Kid == s.signing.ID) != (set.Keys[j].Kid == s.signing.ID) {
+			return set.Keys[i].Kid == s.signing.ID
+		}
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
@@ -0,0 +1,257 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func newEd25519Key(t *testing.T) *Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := newKey(jwt.SigningMethodEdDSA, private, private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRSAKey(t *testing.T) *Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := newKey(jwt.SigningMethodRS256, private, &private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func publicOnly(key *Key) *Key {
+	public := *key
+	public.Private = nil
+	return &public
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func verify(set *KeySet, token string) error {
+	_, err := jwt.Parse(token, set.Keyfunc)
+	return err
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newEd25519Key(t)
+
+	before, err := NewKeySet(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key signs, the old public key still verifies
+	after, err := NewKeySet(newKey, publicOnly(oldKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _ := jwt.Parse(newToken, after.Keyfunc)
+	if parsed == nil || parsed.Header["kid"] != newKey.ID || parsed.Method.Alg() != "EdDSA" {
+		t.Errorf("new token header = %v, want kid %s and EdDSA", parsed.Header, newKey.ID)
+	}
+	if err := verify(after, oldToken); err != nil {
+		t.Errorf("old token rejected after rotation: %v", err)
+	}
+	if err := verify(after, newToken); err != nil {
+		t.Errorf("new token rejected: %v", err)
+	}
+	if err := verify(before, newToken); err == nil {
+		t.Error("a set without the new key accepted its token")
+	}
+
+	// Once the old key is dropped its tokens stop working
+	finished, err := NewKeySet(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(finished, oldToken); err == nil {
+		t.Error("old token accepted after the old key was removed")
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != newKey.ID || jwks.Keys[1].Kid != oldKey.ID {
+		t.Errorf("JWKS = %+v, want the signing key first, then the old key", jwks.Keys)
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	ed, rsaKey := newEd25519Key(t), newRSAKey(t)
+	set, err := NewKeySet(ed, publicOnly(rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header map[string]any
+		method jwt.SigningMethod
+		key    any
+		ok     bool
+	}{
+		{"signing key by kid", map[string]any{"kid": ed.ID}, jwt.SigningMethodEdDSA, ed.Private, true},
+		{"verification key by kid", map[string]any{"kid": rsaKey.ID}, jwt.SigningMethodRS256, rsaKey.Private, true},
+		{"unknown kid", map[string]any{"kid": "nope"}, jwt.SigningMethodEdDSA, ed.Private, false},
+		{"no kid", map[string]any{}, jwt.SigningMethodEdDSA, ed.Private, false},
+		// An HS256 token "signed" with the RSA public key must not verify
+		{"alg substitution", map[string]any{"kid": rsaKey.ID}, jwt.SigningMethodHS256, x509.MarshalPKCS1PublicKey(rsaKey.Public.(*rsa.PublicKey)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(tt.method, claims())
+			for k, v := range tt.header {
+				token.Header[k] = v
+			}
+			signed, err := token.SignedString(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verify(set, signed); (err == nil) != tt.ok {
+				t.Errorf("err = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHMACKeySet(t *testing.T) {
+	set, err := NewKeySet(NewHMACKey("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := set.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(set, token); err != nil {
+		t.Errorf("signed token rejected: %v", err)
+	}
+
+	// Tokens issued before kids existed are still accepted
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(set, legacy); err != nil {
+		t.Errorf("token without kid rejected: %v", err)
+	}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("guess"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(set, forged); err == nil {
+		t.Error("token signed with another secret accepted")
+	}
+
+	if len(set.JWKS().Keys) != 0 {
+		t.Error("HMAC key published in the JWKS")
+	}
+	if _, err := NewKeySet(newEd25519Key(t), NewHMACKey("secret")); err == nil {
+		t.Error("HMAC key accepted as a verification key")
+	}
+	if _, err := NewKeySet(publicOnly(newEd25519Key(t))); err == nil {
+		t.Error("public key accepted as the signing key")
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	for _, key := range []*Key{newEd25519Key(t), newRSAKey(t)} {
+		jwk := key.JWK()
+		back, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back.ID != key.ID || back.Method != key.Method || back.Private != nil {
+			t.Errorf("%s: round trip gave kid %s, %s", jwk.Kty, back.ID, back.Method.Alg())
+		}
+
+		// Without a kid the thumbprint is computed again and matches
+		jwk.Kid = ""
+		if back, err := jwk.PublicKey(); err != nil || back.ID != key.ID {
+			t.Errorf("%s: thumbprint kid = %v, %v; want %s", jwk.Kty, back, err, key.ID)
+		}
+	}
+
+	if _, err := (JWK{Kty: "OKP", Crv: "X25519", X: "AAAA"}).PublicKey(); err == nil {
+		t.Error("X25519 key accepted")
+	}
+	if _, err := (JWK{Kty: "EC"}).PublicKey(); err == nil {
+		t.Error("EC key accepted")
+	}
+}
+
+func TestLoadKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The kid depends on the key only, not on the file format
+	signing, err := LoadPrivateKeyFile(write("pkcs1.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := LoadPublicKeyFile(write("public.pem", "PUBLIC KEY", pkix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromPrivate, err := LoadPublicKeyFile(filepath.Join(dir, "pkcs1.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signing.ID != public.ID || signing.ID != fromPrivate.ID {
+		t.Errorf("kids differ: %s, %s, %s", signing.ID, public.ID, fromPrivate.ID)
+	}
+	if signing.Method != jwt.SigningMethodRS256 || public.Private != nil || fromPrivate.Private != nil {
+		t.Error("unexpected method or private part")
+	}
+
+	if _, err := LoadPrivateKeyFile(write("cert.pem", "CERTIFICATE", []byte("x"))); err == nil {
+		t.Error("certificate accepted as a private key")
+	}
+	if _, err := LoadPrivateKeyFile(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("missing file accepted")
+	}
+}
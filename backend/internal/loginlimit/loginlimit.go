@@ -0,0 +1,170 @@
+// Package loginlimit throttles password guessing. Failed logins are counted
+// per key (the application uses one key for the account email and one for
+// the client IP). Once a key exceeds its free attempts it is locked for an
+// exponentially growing period.
+package loginlimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// State is what a Store keeps per key.
+type State struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store persists per-key state. Increment must be atomic so concurrent
+// failures are never lost.
+type Store interface {
+	Get(ctx context.Context, key string) (State, error)
+	// Increment records a failure at now and returns the new failure count.
+	// A count whose last failure is older than window starts over at one.
+	Increment(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy describes how quickly a key gets locked.
+type Policy struct {
+	FreeAttempts int           // failures allowed before the first lockout
+	BaseLockout  time.Duration // lockout after the first failure past FreeAttempts
+	MaxLockout   time.Duration // upper bound for the doubling lockout
+	Window       time.Duration // quiet period after which failures are forgotten
+}
+
+// Lockout returns how long a key with the given number of failures is locked.
+func (p Policy) Lockout(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	d := p.BaseLockout
+	for i := 1; i < over && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	if d > p.MaxLockout {
+		d = p.MaxLockout
+	}
+	return d
+}
+
+// Limiter applies a Policy to keys kept in a Store.
+type Limiter struct {
+	Store Store
+	now   func() time.Time
+}
+
+func New(store Store) *Limiter {
+	return &Limiter{Store: store, now: time.Now}
+}
+
+// Check returns how long the caller has to wait before trying again. Zero
+// means none of the keys is locked.
+func (l *Limiter) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	now := l.now()
+	var wait time.Duration
+	for _, key := range keys {
+		state, err := l.Store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if d := state.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// Failure records a failed attempt for key. When this failure locks the key
+// the lockout duration is returned, otherwise zero.
+func (l *Limiter) Failure(ctx context.Context, key string, policy Policy) (time.Duration, error) {
+	now := l.now()
+	failures, err := l.Store.Increment(ctx, key, now, policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	lockout := policy.Lockout(failures)
+	if lockout == 0 {
+		return 0, nil
+	}
+	if err := l.Store.Lock(ctx, key, now.Add(lockout)); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+// Success forgets the failures recorded for key.
+func (l *Limiter) Success(ctx context.Context, key string) error {
+	return l.Store.Reset(ctx, key)
+}
+
+// MemoryStore keeps state in process memory. It suits a single node; the
+// state is lost on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string]State{}}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+func (s *MemoryStore) Increment(_ context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.states[key]
+	if now.Sub(state.LastFailureAt) > window {
+		state.Failures = 0
+	}
+	state.Failures++
+	state.LastFailureAt = now
+	s.states[key] = state
+
+	s.sweep(now, window)
+	return state.Failures, nil
+}
+
+func (s *MemoryStore) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.states[key]
+	if until.After(state.LockedUntil) {
+		state.LockedUntil = until
+	}
+	s.states[key] = state
+	return nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+// sweep drops keys that are neither locked nor within the window so the map
+// can't grow without bound under a spray of random emails.
+func (s *MemoryStore) sweep(now time.Time, window time.Duration) {
+	if len(s.states) < 10000 {
+		return
+	}
+	for key, state := range s.states {
+		if now.Sub(state.LastFailureAt) > window && now.After(state.LockedUntil) {
+			delete(s.states, key)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package loginlimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseLockout:  30 * time.Second,
+	MaxLockout:   5 * time.Minute,
+	Window:       time.Hour,
+}
+
+func TestPolicyLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, 30 * time.Second},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{8, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.Lockout(tt.failures); got != tt.want {
+			t.Errorf("Lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// newTestLimiter returns a limiter on a memory store whose clock only moves
+// when the test advances it.
+func newTestLimiter() (*Limiter, func(time.Duration)) {
+	now := time.Unix(1700000000, 0)
+	l := New(NewMemoryStore())
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l, advance := newTestLimiter()
+
+	for i := 1; i <= testPolicy.FreeAttempts; i++ {
+		if lockout, err := l.Failure(ctx, "email:ann", testPolicy); err != nil || lockout != 0 {
+			t.Fatalf("failure %d: lockout %v, %v", i, lockout, err)
+		}
+	}
+	if wait, _ := l.Check(ctx, "email:ann"); wait != 0 {
+		t.Fatalf("locked after the free attempts: %v", wait)
+	}
+
+	if lockout, _ := l.Failure(ctx, "email:ann", testPolicy); lockout != 30*time.Second {
+		t.Fatalf("first lockout = %v, want 30s", lockout)
+	}
+	advance(10 * time.Second)
+	if wait, _ := l.Check(ctx, "ip:1", "email:ann"); wait != 20*time.Second {
+		t.Errorf("wait = %v, want the longest of the keys, 20s", wait)
+	}
+	advance(20 * time.Second)
+	if wait, _ := l.Check(ctx, "email:ann"); wait != 0 {
+		t.Errorf("still locked after the lockout: %v", wait)
+	}
+
+	// Every further failure doubles the lockout
+	if lockout, _ := l.Failure(ctx, "email:ann", testPolicy); lockout != time.Minute {
+		t.Errorf("second lockout = %v, want 1m", lockout)
+	}
+
+	if err := l.Success(ctx, "email:ann"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := l.Check(ctx, "email:ann"); wait != 0 {
+		t.Errorf("locked after a success: %v", wait)
+	}
+	if lockout, _ := l.Failure(ctx, "email:ann", testPolicy); lockout != 0 {
+		t.Errorf("success did not forget the failures: lockout %v", lockout)
+	}
+}
+
+func TestLimiterForgetsAfterWindow(t *testing.T) {
+	ctx := context.Background()
+	l, advance := newTestLimiter()
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		l.Failure(ctx, "email:ann", testPolicy)
+	}
+	advance(testPolicy.Window + time.Second)
+	if lockout, _ := l.Failure(ctx, "email:ann", testPolicy); lockout != 0 {
+		t.Errorf("failures older than the window still count: lockout %v", lockout)
+	}
+}
+
+func TestMemoryStoreLockNeverShortens(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+
+	s.Lock(ctx, "k", now.Add(time.Hour))
+	s.Lock(ctx, "k", now.Add(time.Minute))
+	if state, _ := s.Get(ctx, "k"); !state.LockedUntil.Equal(now.Add(time.Hour)) {
+		t.Errorf("locked until %v, want %v", state.LockedUntil, now.Add(time.Hour))
+	}
+}
+
+func TestMemoryStoreConcurrentIncrement(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Increment(ctx, "k", now, time.Hour)
+		}()
+	}
+	wg.Wait()
+	if state, _ := s.Get(ctx, "k"); state.Failures != 50 {
+		t.Errorf("failures = %d, want 50", state.Failures)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	old := time.Unix(1700000000, 0)
+
+	for i := 0; i < 10000; i++ {
+		s.Increment(ctx, string(rune(0x10000+i)), old, time.Hour)
+	}
+	s.Lock(ctx, string(rune(0x10000)), old.Add(24*time.Hour))
+	s.Increment(ctx, "fresh", old.Add(2*time.Hour), time.Hour)
+
+	if len(s.states) != 2 {
+		t.Errorf("%d keys left after the sweep, want the locked and the fresh one", len(s.states))
+	}
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatRejectsLineBreaks(t *testing.T) {
+	m := &SMTPMailer{From: "events@example.com"}
+	tests := []Message{
+		{To: "ann@example.com", Subject: "You're in: Party\r\nBcc: all@example.com"},
+		{To: "ann@example.com", Subject: "You're in: Party\nBcc: all@example.com"},
+		{To: "ann@example.com\r\nBcc: all@example.com", Subject: "Hello"},
+	}
+	for _, msg := range tests {
+		if _, err := m.format(msg); !errors.Is(err, ErrHeaderLineBreak) {
+			t.Errorf("format(%q) error = %v, want ErrHeaderLineBreak", msg.Subject, err)
+		}
+	}
+}
+
+func TestFormatEncodesSubject(t *testing.T) {
+	m := &SMTPMailer{From: "events@example.com"}
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Verify your email address", "Subject: Verify your email address\r\n"},
+		{"You're in: Café night", "Subject: =?utf-8?q?You're_in:_Caf=C3=A9_night?=\r\n"},
+	}
+	for _, tt := range tests {
+		data, err := m.format(Message{To: "ann@example.com", Subject: tt.subject, Body: "line one\nline two"})
+		if err != nil {
+			t.Fatalf("format(%q): %v", tt.subject, err)
+		}
+		header, body, ok := strings.Cut(string(data), "\r\n\r\n")
+		if !ok {
+			t.Fatalf("format(%q) has no end of headers", tt.subject)
+		}
+		if !strings.Contains(header+"\r\n", tt.want) {
+			t.Errorf("format(%q) headers = %q, want %q", tt.subject, header, tt.want)
+		}
+		if body != "line one\r\nline two" {
+			t.Errorf("format(%q) body = %q", tt.subject, body)
+		}
+	}
+}
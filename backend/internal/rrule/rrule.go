@@ -0,0 +1,526 @@
+// Package rrule parses RFC 5545 recurrence rules and expands them into
+// occurrences. It supports what calendars use for meetings and meetups:
+// FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY, BYMONTH, BYSETPOS and WKST. Occurrences keep the time of day
+// of the series start, in its location.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is one BYDAY entry. N picks the Nth such weekday of the month
+// (or year), counting from the end when negative; 0 means every one.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed RRULE. Until is zero when the rule has no end date;
+// Count is 0 when it has no occurrence limit.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	// untilDate is set when UNTIL was a date without a time, which includes
+	// the whole day wherever the series takes place.
+	untilDate bool
+}
+
+// horizonYears bounds how far after the series start occurrences are
+// looked for, so rules that can never match (BYMONTH=2;BYMONTHDAY=30) end,
+// and end quickly.
+const horizonYears = 100
+
+// periodsPerYear is how many days, weeks, months or years a year spans at
+// most.
+var periodsPerYear = map[Frequency]int{Daily: 366, Weekly: 53, Monthly: 12, Yearly: 1}
+
+var dayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func parseDay(s string) (time.Weekday, error) {
+	i := slices.Index(dayNames, s)
+	if i < 0 {
+		return 0, fmt.Errorf("invalid weekday %q", s)
+	}
+	return time.Weekday(i), nil
+}
+
+// parseInts parses a comma separated list of integers that must lie in
+// [-limit, limit] and may only be negative when negative is true; 0 is
+// never allowed.
+func parseInts(part, value string, limit int, negative bool) ([]int, error) {
+	var out []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+		if err != nil || n == 0 || n > limit || n < -limit || (n < 0 && !negative) {
+			return nil, fmt.Errorf("invalid %s value %q", part, s)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseUntil(value string) (t time.Time, dateOnly bool, err error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid UNTIL value %q", value)
+}
+
+// Parse parses a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10". A leading
+// "RRULE:" is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty rule")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+		case "UNTIL":
+			if r.Until, r.untilDate, err = parseUntil(value); err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, s := range strings.Split(value, ",") {
+				if len(s) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY value %q", s)
+				}
+				day, err := parseDay(s[len(s)-2:])
+				if err != nil {
+					return nil, err
+				}
+				wd := WeekdayNum{Day: day}
+				if prefix := s[:len(s)-2]; prefix != "" {
+					n, err := parseInts("BYDAY", prefix, 53, true)
+					if err != nil {
+						return nil, err
+					}
+					wd.N = n[0]
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = parseInts(name, value, 31, true); err != nil {
+				return nil, err
+			}
+		case "BYMONTH":
+			months, err := parseInts(name, value, 12, false)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			if r.BySetPos, err = parseInts(name, value, 366, true); err != nil {
+				return nil, err
+			}
+		case "WKST":
+			if r.WeekStart, err = parseDay(value); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, errors.New("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return nil, errors.New("COUNT and UNTIL cannot be used together")
+	case r.Freq == Weekly && len(r.ByMonthDay) > 0:
+		return nil, errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	case len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0:
+		return nil, errors.New("BYSETPOS needs BYDAY, BYMONTHDAY or BYMONTH")
+	}
+	if r.Freq == Daily || r.Freq == Weekly {
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return nil, fmt.Errorf("numbered BYDAY values need FREQ=MONTHLY or YEARLY")
+			}
+		}
+	}
+	return r, nil
+}
+
+// String formats the rule the way Parse reads it, without the "RRULE:"
+// prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.untilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = dayNames[wd.Day]
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// SetUntil ends the series at t (inclusive), dropping any COUNT.
+func (r *Rule) SetUntil(t time.Time) {
+	r.Count = 0
+	r.Until = t.UTC()
+	r.untilDate = false
+}
+
+// InLocation returns the rule with an UNTIL date turned into the last second
+// of that day in loc, where a series starting in loc ends. RFC 5545 wants
+// UNTIL to be a time when the series start is one.
+func (r *Rule) InLocation(loc *time.Location) *Rule {
+	out := *r
+	if r.untilDate {
+		out.SetUntil(time.Date(r.Until.Year(), r.Until.Month(), r.Until.Day(), 23, 59, 59, 0, loc))
+	}
+	return &out
+}
+
+// Between returns the occurrences of a series starting at start that fall in
+// [from, to), leaving out exdates. It stops after limit occurrences unless
+// limit is 0. Like RFC 5545 says, start itself is always the first
+// occurrence, even if the rule would not produce it.
+func (r *Rule) Between(start, from, to time.Time, exdates []time.Time, limit int) []time.Time {
+	excluded := make(map[int64]bool, len(exdates))
+	for _, t := range exdates {
+		excluded[t.Unix()] = true
+	}
+
+	var out []time.Time
+	r.each(start, func(t time.Time, _ int) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) && !excluded[t.Unix()] {
+			out = append(out, t)
+		}
+		return limit == 0 || len(out) < limit
+	})
+	return out
+}
+
+// Index returns the position of t among the occurrences of a series
+// starting at start, counting from 0, or -1 when the rule does not produce
+// t. Excluded dates still count, as they do for COUNT.
+func (r *Rule) Index(start, t time.Time) int {
+	index := -1
+	r.each(start, func(o time.Time, i int) bool {
+		if o.Equal(t) {
+			index = i
+		}
+		return o.Before(t)
+	})
+	return index
+}
+
+// Repeats reports whether the rule produces any occurrence after start
+// within a hundred years, leaving COUNT and UNTIL aside. Rules that do not,
+// such as FREQ=YEARLY;BYDAY=1MO;BYMONTHDAY=31, can be parsed but never
+// repeat.
+func (r *Rule) Repeats(start time.Time) bool {
+	open := *r
+	open.Count, open.Until, open.untilDate = 0, time.Time{}, false
+	repeats := false
+	open.each(start, func(_ time.Time, i int) bool {
+		repeats = i > 0
+		return !repeats
+	})
+	return repeats
+}
+
+// each calls yield with every occurrence in order and its index until yield
+// returns false, the series ends or the horizon is reached.
+func (r *Rule) each(start time.Time, yield func(t time.Time, i int) bool) {
+	loc := start.Location()
+	until := r.Until
+	if r.untilDate {
+		until = time.Date(until.Year(), until.Month(), until.Day(), 23, 59, 59, 0, loc)
+	}
+
+	n := 0
+	emit := func(t time.Time) bool {
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+		if !yield(t, n) {
+			return false
+		}
+		n++
+		return r.Count == 0 || n < r.Count
+	}
+
+	if !emit(start) {
+		return
+	}
+	for period := 0; period*r.Interval <= horizonYears*periodsPerYear[r.Freq]; period++ {
+		for _, day := range r.period(start, period) {
+			t := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the days of the rule's period-th day, week, month or year
+// after start, in order, as midnight UTC dates.
+func (r *Rule) period(start time.Time, period int) []time.Time {
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	step := period * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := first.AddDate(0, 0, step)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		offset := (int(first.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := first.AddDate(0, 0, 7*step-offset)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != first.Weekday() {
+				continue
+			}
+			if r.matchesWeekday(day) && r.matchesMonth(day) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		month := time.Date(first.Year(), first.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(month) {
+			days = r.monthDays(month, first.Day())
+		}
+	case Yearly:
+		year := first.Year() + step
+		switch {
+		case len(r.ByMonth) > 0:
+			for m := time.January; m <= time.December; m++ {
+				if slices.Contains(r.ByMonth, m) {
+					days = append(days, r.monthDays(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC), first.Day())...)
+				}
+			}
+		case len(r.ByDay) > 0:
+			days = r.yearWeekdays(year)
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				days = append(days, r.monthDays(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC), first.Day())...)
+			}
+		default:
+			day := time.Date(year, first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+			if day.Day() == first.Day() {
+				days = append(days, day)
+			}
+		}
+	}
+
+	return r.setPos(days)
+}
+
+// monthDays returns the days of month the rule picks; defaultDay is used
+// when the rule names neither weekdays nor days of the month.
+func (r *Rule) monthDays(month time.Time, defaultDay int) []time.Time {
+	length := month.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+	switch {
+	case len(r.ByDay) > 0:
+		for d := 1; d <= length; d++ {
+			day := month.AddDate(0, 0, d-1)
+			if r.matchesMonthDay(day) && r.matchesNthWeekday(day, d, length) {
+				days = append(days, day)
+			}
+		}
+	case len(r.ByMonthDay) > 0:
+		for d := 1; d <= length; d++ {
+			day := month.AddDate(0, 0, d-1)
+			if r.matchesMonthDay(day) {
+				days = append(days, day)
+			}
+		}
+	case defaultDay <= length:
+		days = append(days, month.AddDate(0, 0, defaultDay-1))
+	}
+	return days
+}
+
+// yearWeekdays returns the days of year matching BYDAY, where numbered
+// weekdays count within the year, then narrowed down by BYMONTHDAY.
+func (r *Rule) yearWeekdays(year int) []time.Time {
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	length := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+
+	var days []time.Time
+	for d := 1; d <= length; d++ {
+		day := jan1.AddDate(0, 0, d-1)
+		if r.matchesMonthDay(day) && r.matchesNthWeekday(day, d, length) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// matchesNthWeekday reports whether day, the pos-th of length days in its
+// month or year, matches one of the BYDAY entries.
+func (r *Rule) matchesNthWeekday(day time.Time, pos, length int) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case wd.N > 0 && (pos-1)/7+1 == wd.N:
+			return true
+		case wd.N < 0 && (length-pos)/7+1 == -wd.N:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonth(day time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, day.Month())
+}
+
+func (r *Rule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := day.AddDate(0, 1, -day.Day()).Day()
+	for _, d := range r.ByMonthDay {
+		if d == day.Day() || (d < 0 && length+1+d == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// setPos applies BYSETPOS to the days of one period.
+func (r *Rule) setPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+	var picked []time.Time
+	for i, day := range days {
+		for _, pos := range r.BySetPos {
+			if pos == i+1 || pos == i-len(days) {
+				picked = append(picked, day)
+				break
+			}
+		}
+	}
+	return picked
+}
@@ -0,0 +1,352 @@
+package rrule
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+// at parses a "2006-01-02 15:04" wall time in loc.
+func at(t *testing.T, loc *time.Location, s string) time.Time {
+	t.Helper()
+	v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return v
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // String() of the result; "" when Parse must fail
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=mo,we;count=10", "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"},
+		{"FREQ=WEEKLY;INTERVAL=1;WKST=MO", "FREQ=WEEKLY"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=TU", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU;WKST=SU"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYDAY=+2MO", "FREQ=MONTHLY;BYDAY=2MO"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{"FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=-1;UNTIL=20301231", "FREQ=YEARLY;UNTIL=20301231;BYMONTH=6;BYMONTHDAY=-1"},
+		{"FREQ=DAILY;UNTIL=20300101T120000Z", "FREQ=DAILY;UNTIL=20300101T120000Z"},
+
+		{"", ""},
+		{"RRULE:", ""},
+		{"COUNT=3", ""},
+		{"FREQ=HOURLY", ""},
+		{"FREQ=DAILY;FREQ=WEEKLY", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=DAILY;COUNT=-1", ""},
+		{"FREQ=DAILY;COUNT=2;UNTIL=20300101", ""},
+		{"FREQ=DAILY;UNTIL=2030-01-01", ""},
+		{"FREQ=DAILY;BYSECOND=1", ""},
+		{"FREQ=DAILY;BYDAY", ""},
+		{"FREQ=DAILY;BYDAY=XX", ""},
+		{"FREQ=DAILY;BYDAY=1MO", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=YEARLY;BYMONTH=13", ""},
+		{"FREQ=YEARLY;BYMONTH=-1", ""},
+		{"FREQ=MONTHLY;BYSETPOS=1", ""},
+		{"FREQ=MONTHLY;BYDAY=0MO", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			r, err := Parse(tt.in)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %q, want an error", tt.in, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.in, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+			}
+			// String must parse back to the same rule
+			again, err := Parse(r.String())
+			if err != nil || again.String() != tt.want {
+				t.Errorf("round trip of %q = %v, %v", tt.want, again, err)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	utc := time.UTC
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name    string
+		rule    string
+		start   string
+		loc     *time.Location
+		from    string // "" for the start
+		to      string // "" for ten years after the start
+		exdates []string
+		limit   int
+		want    []string // wall times in loc
+	}{
+		{
+			name:  "daily count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2030-01-01 10:00", loc: utc,
+			want: []string{"2030-01-01 10:00", "2030-01-02 10:00", "2030-01-03 10:00"},
+		},
+		{
+			name:  "every other day",
+			rule:  "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			start: "2030-01-01 10:00", loc: utc,
+			want: []string{"2030-01-01 10:00", "2030-01-03 10:00", "2030-01-05 10:00"},
+		},
+		{
+			name:  "weekly on two days",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			start: "2030-01-07 18:30", loc: utc,
+			want: []string{"2030-01-07 18:30", "2030-01-09 18:30", "2030-01-14 18:30", "2030-01-16 18:30"},
+		},
+		{
+			name:  "weekly without BYDAY keeps the weekday",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: "2030-01-09 08:00", loc: utc,
+			want: []string{"2030-01-09 08:00", "2030-01-16 08:00", "2030-01-23 08:00"},
+		},
+		{
+			name:  "start is the first occurrence even off the rule",
+			rule:  "FREQ=WEEKLY;BYDAY=FR;COUNT=3",
+			start: "2030-01-07 12:00", loc: utc,
+			want: []string{"2030-01-07 12:00", "2030-01-11 12:00", "2030-01-18 12:00"},
+		},
+		{
+			name:  "monthly on the last Friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: "2030-01-25 19:00", loc: utc,
+			want: []string{"2030-01-25 19:00", "2030-02-22 19:00", "2030-03-29 19:00"},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: "2030-01-31 09:00", loc: utc,
+			want: []string{"2030-01-31 09:00", "2030-03-31 09:00", "2030-05-31 09:00"},
+		},
+		{
+			name:  "monthly on the last day",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			start: "2030-01-31 09:00", loc: utc,
+			want: []string{"2030-01-31 09:00", "2030-02-28 09:00", "2030-03-31 09:00"},
+		},
+		{
+			name:  "BYSETPOS picks the last weekday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3",
+			start: "2030-01-31 17:00", loc: utc,
+			want: []string{"2030-01-31 17:00", "2030-02-28 17:00", "2030-03-29 17:00"},
+		},
+		{
+			name:  "BYSETPOS picks the first and second weekday",
+			rule:  "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=1,2;COUNT=4",
+			start: "2030-02-01 17:00", loc: utc,
+			want: []string{"2030-02-01 17:00", "2030-02-04 17:00", "2030-03-01 17:00", "2030-03-04 17:00"},
+		},
+		{
+			name:  "yearly on the first Monday",
+			rule:  "FREQ=YEARLY;BYDAY=1MO;COUNT=3",
+			start: "2030-01-07 10:00", loc: utc,
+			want: []string{"2030-01-07 10:00", "2031-01-06 10:00", "2032-01-05 10:00"},
+		},
+		{
+			name:  "yearly on leap day",
+			rule:  "FREQ=YEARLY;COUNT=2",
+			start: "2028-02-29 10:00", loc: utc,
+			want: []string{"2028-02-29 10:00", "2032-02-29 10:00"},
+		},
+		{
+			name:  "UNTIL as a date includes that whole local day",
+			rule:  "FREQ=DAILY;UNTIL=20300103",
+			start: "2030-01-01 23:30", loc: berlin,
+			want: []string{"2030-01-01 23:30", "2030-01-02 23:30", "2030-01-03 23:30"},
+		},
+		{
+			name:  "UNTIL as a time is an instant",
+			rule:  "FREQ=DAILY;UNTIL=20300103T120000Z",
+			start: "2030-01-01 18:00", loc: berlin,
+			want: []string{"2030-01-01 18:00", "2030-01-02 18:00"},
+		},
+		{
+			name:  "UNTIL exactly on an occurrence includes it",
+			rule:  "FREQ=DAILY;UNTIL=20300102T170000Z",
+			start: "2030-01-01 18:00", loc: berlin,
+			want: []string{"2030-01-01 18:00", "2030-01-02 18:00"},
+		},
+		{
+			name:  "window, exdates and limit",
+			rule:  "FREQ=DAILY",
+			start: "2030-01-01 10:00", loc: utc,
+			from: "2030-01-03 00:00", to: "2030-01-10 00:00",
+			exdates: []string{"2030-01-04 10:00"},
+			limit:   3,
+			want:    []string{"2030-01-03 10:00", "2030-01-05 10:00", "2030-01-06 10:00"},
+		},
+		{
+			name:  "the end of the window is exclusive",
+			rule:  "FREQ=DAILY",
+			start: "2030-01-01 10:00", loc: utc,
+			to:   "2030-01-03 10:00",
+			want: []string{"2030-01-01 10:00", "2030-01-02 10:00"},
+		},
+		{
+			name:  "spring forward keeps the local time",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2030-03-30 09:00", loc: berlin,
+			want: []string{"2030-03-30 09:00", "2030-03-31 09:00", "2030-04-01 09:00"},
+		},
+		{
+			name:  "fall back keeps the local time",
+			rule:  "FREQ=WEEKLY;BYDAY=SA,SU;COUNT=3",
+			start: "2030-10-26 09:00", loc: berlin,
+			want: []string{"2030-10-26 09:00", "2030-10-27 09:00", "2030-11-02 09:00"},
+		},
+		{
+			name:  "a rule that never matches only has the start",
+			rule:  "FREQ=YEARLY;BYDAY=1MO;BYMONTHDAY=31",
+			start: "2030-01-07 10:00", loc: utc,
+			to:   "3000-01-01 00:00",
+			want: []string{"2030-01-07 10:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			start := at(t, tt.loc, tt.start)
+			from, to := start, start.AddDate(10, 0, 0)
+			if tt.from != "" {
+				from = at(t, tt.loc, tt.from)
+			}
+			if tt.to != "" {
+				to = at(t, tt.loc, tt.to)
+			}
+			var exdates []time.Time
+			for _, s := range tt.exdates {
+				exdates = append(exdates, at(t, tt.loc, s))
+			}
+
+			var got []string
+			for _, o := range r.Between(start, from, to, exdates, tt.limit) {
+				if o.Location() != tt.loc {
+					t.Errorf("occurrence %v is not in %v", o, tt.loc)
+				}
+				got = append(got, o.Format("2006-01-02 15:04"))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Between = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetweenDSTOffsets(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	r, err := Parse("FREQ=DAILY;COUNT=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Europe switches to summer time on 31 March 2030, so the same local
+	// time is an hour earlier in UTC
+	start := at(t, berlin, "2030-03-30 09:00")
+	got := r.Between(start, start, start.AddDate(0, 0, 7), nil, 0)
+	want := []string{"2030-03-30T08:00:00Z", "2030-03-31T07:00:00Z"}
+	if len(got) != len(want) {
+		t.Fatalf("Between = %v, want %v", got, want)
+	}
+	for i := range got {
+		if s := got[i].UTC().Format(time.RFC3339); s != want[i] {
+			t.Errorf("occurrence %d = %s, want %s", i, s, want[i])
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	r, err := Parse("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := at(t, time.UTC, "2030-01-07 18:30")
+
+	tests := []struct {
+		t    string
+		want int
+	}{
+		{"2030-01-07 18:30", 0},
+		{"2030-01-09 18:30", 1},
+		{"2030-01-16 18:30", 3},
+		{"2030-01-21 18:30", -1}, // after COUNT
+		{"2030-01-08 18:30", -1}, // a Tuesday
+		{"2030-01-09 18:00", -1}, // wrong time of day
+		{"2030-01-01 18:30", -1}, // before the start
+	}
+	for _, tt := range tests {
+		if got := r.Index(start, at(t, time.UTC, tt.t)); got != tt.want {
+			t.Errorf("Index(%s) = %d, want %d", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestInLocation(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	r, err := Parse("FREQ=DAILY;UNTIL=20300103")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.InLocation(berlin).String(), "FREQ=DAILY;UNTIL=20300103T225959Z"; got != want {
+		t.Errorf("InLocation = %q, want %q", got, want)
+	}
+	if got := r.String(); got != "FREQ=DAILY;UNTIL=20300103" {
+		t.Errorf("InLocation changed the rule to %q", got)
+	}
+}
+
+func TestRepeats(t *testing.T) {
+	start := at(t, time.UTC, "2030-01-07 10:00")
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{"FREQ=DAILY", true},
+		{"FREQ=DAILY;COUNT=1", true},
+		{"FREQ=DAILY;UNTIL=20300101", true},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", true},
+		{"FREQ=YEARLY;INTERVAL=4;BYMONTH=2;BYMONTHDAY=29", false}, // 2030, 2034, ... are not leap years
+		{"FREQ=YEARLY;BYDAY=1MO;BYMONTHDAY=31", false},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", false},
+		{"FREQ=DAILY;BYMONTH=4;BYMONTHDAY=31", false},
+		{"FREQ=YEARLY;INTERVAL=200", false},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		begin := time.Now()
+		if got := r.Repeats(start); got != tt.want {
+			t.Errorf("Repeats(%q) = %v, want %v", tt.rule, got, tt.want)
+		}
+		if d := time.Since(begin); d > time.Second {
+			t.Errorf("Repeats(%q) took %v", tt.rule, d)
+		}
+	}
+}
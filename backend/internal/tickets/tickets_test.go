@@ -0,0 +1,61 @@
+package tickets
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	signer := NewSigner("secret")
+	plain := signer.Code(Ticket{EventId: 7, AttendeeId: 42})
+	series := signer.Code(Ticket{EventId: 7, AttendeeId: 42, Occurrence: "2030-01-07T18:00:00Z"})
+	mac := plain[strings.LastIndex(plain, ".")+1:]
+	seriesMAC := series[strings.LastIndex(series, ".")+1:]
+
+	// flip changes one bit of the code's MAC.
+	flip := func(code string) string {
+		i := strings.LastIndex(code, ".") + 1
+		b := []byte(code)
+		if b[i] == 'A' {
+			b[i] = 'B'
+		} else {
+			b[i] = 'A'
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want *Ticket
+	}{
+		{"ticket", plain, &Ticket{EventId: 7, AttendeeId: 42}},
+		{"occurrence ticket", series, &Ticket{EventId: 7, AttendeeId: 42, Occurrence: "2030-01-07T18:00:00Z"}},
+		{"surrounding space", " " + plain + "\n", &Ticket{EventId: 7, AttendeeId: 42}},
+		{"other event", "8.42." + mac, nil},
+		{"other attendee", "7.43." + mac, nil},
+		{"other occurrence", "7.42.2030-01-14T18:00:00Z." + seriesMAC, nil},
+		{"occurrence dropped", "7.42." + seriesMAC, nil},
+		{"occurrence added", "7.42.2030-01-07T18:00:00Z." + mac, nil},
+		{"changed MAC", flip(plain), nil},
+		{"truncated MAC", plain[:len(plain)-2], nil},
+		{"other key", NewSigner("other").Code(Ticket{EventId: 7, AttendeeId: 42}), nil},
+		{"not base64", "7.42.!!!", nil},
+		{"not a number", "seven.42." + mac, nil},
+		{"too few parts", "7." + mac, nil},
+		{"too many parts", "7.42.a.b." + mac, nil},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := signer.Parse(tt.code)
+		if tt.want == nil {
+			if err != ErrInvalid {
+				t.Errorf("%s: Parse(%q) = %+v, %v; want ErrInvalid", tt.name, tt.code, got, err)
+			}
+			continue
+		}
+		if err != nil || got != *tt.want {
+			t.Errorf("%s: Parse(%q) = %+v, %v; want %+v", tt.name, tt.code, got, err, *tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks secrets encrypted by a SecretBox. Secrets stored
+// before encryption was added are plain base32, which never contains ':'.
+const sealedPrefix = "v1:"
+
+// ErrUnsealable is returned for stored secrets that were not sealed with the
+// box's key or were tampered with.
+var ErrUnsealable = errors.New("totp: cannot decrypt secret")
+
+// SecretBox encrypts TOTP secrets for storage with AES-256-GCM, so a copy
+// of the database alone does not give away anyone's second factor.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox derives the encryption key from key, which can be any
+// string; a long random one is best.
+func NewSecretBox(key string) *SecretBox {
+	sum := sha256.Sum256([]byte("totp-secret:" + key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		panic(err) // a 32-byte key is always valid
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &SecretBox{aead: aead}
+}
+
+// Seal encrypts secret for storage.
+func (b *SecretBox) Seal(secret string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open returns the secret stored as stored. Secrets stored in the clear
+// are returned as they are; see Sealed.
+func (b *SecretBox) Open(stored string) (string, error) {
+	if !Sealed(stored) {
+		return stored, nil
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrUnsealable
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrUnsealable
+	}
+	return string(secret), nil
+}
+
+// Sealed reports whether stored was encrypted by a SecretBox.
+func Sealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedPrefix)
+}
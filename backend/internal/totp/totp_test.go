@@ -0,0 +1,137 @@
+package totp
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeAt(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := CodeAt(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+
+	if code, err := CodeAt(strings.ToLower(rfcSecret), 1); err != nil || code != "287082" {
+		t.Errorf("lower-case secret: %q, %v", code, err)
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Counter(now)
+	codeAt := func(offset int64) string {
+		code, err := CodeAt(rfcSecret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		skew    int
+		counter int64
+		ok      bool
+	}{
+		{"current step", codeAt(0), 1, step, true},
+		{"previous step", codeAt(-1), 1, step - 1, true},
+		{"next step", codeAt(1), 1, step + 1, true},
+		{"two steps behind", codeAt(-2), 1, 0, false},
+		{"two steps ahead", codeAt(2), 1, 0, false},
+		{"previous step without skew", codeAt(-1), 0, 0, false},
+		{"spaces", " " + codeAt(0)[:3] + " " + codeAt(0)[3:] + " ", 1, step, true},
+		{"too short", codeAt(0)[:5], 1, 0, false},
+		{"too long", codeAt(0) + "0", 1, 0, false},
+		{"empty", "", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.ok || counter != tt.counter {
+				t.Errorf("Validate(%q) = %d, %v; want %d, %v", tt.code, counter, ok, tt.counter, tt.ok)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("My App", "ann@example.com", rfcSecret)
+	for _, part := range []string{"otpauth://totp/My%20App:ann@example.com?", "secret=" + rfcSecret, "issuer=My+App", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("%s does not contain %s", uri, part)
+		}
+	}
+}
+
+func TestSecretBox(t *testing.T) {
+	box := NewSecretBox("server-key")
+
+	sealed, err := box.Seal(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Sealed(sealed) || strings.Contains(sealed, rfcSecret) {
+		t.Fatalf("Seal returned %q", sealed)
+	}
+	if again, _ := box.Seal(rfcSecret); again == sealed {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+	if secret, err := box.Open(sealed); err != nil || secret != rfcSecret {
+		t.Errorf("Open = %q, %v; want %q", secret, err, rfcSecret)
+	}
+
+	// Secrets stored before encryption come back as they are
+	if Sealed(rfcSecret) {
+		t.Error("plain secret reported as sealed")
+	}
+	if secret, err := box.Open(rfcSecret); err != nil || secret != rfcSecret {
+		t.Errorf("Open(plain) = %q, %v", secret, err)
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	tampered := sealedPrefix + base64.RawStdEncoding.EncodeToString(data)
+	for name, stored := range map[string]string{
+		"wrong key": sealed,
+		"tampered":  tampered,
+		"truncated": sealedPrefix + "AAAA",
+		"garbage":   sealedPrefix + "!!",
+	} {
+		b := box
+		if name == "wrong key" {
+			b = NewSecretBox("other-key")
+		}
+		if _, err := b.Open(stored); !errors.Is(err, ErrUnsealable) {
+			t.Errorf("%s: err = %v, want ErrUnsealable", name, err)
+		}
+	}
+}